@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/pkg/authors"
+	"github.com/euforic/todos/todos"
+)
+
+// annotator sets the comment fields that come from project files rather
+// than from the comments themselves
+type annotator struct {
+	owners     *codeOwners
+	authors    *authors.Map
+	severities map[string]string
+}
+
+// loadAnnotator loads the CODEOWNERS and author alias files for dir
+func loadAnnotator(cfg *config.Config, dir string) (*annotator, error) {
+	owners, err := loadCodeOwners(cfg, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := loadAuthors(cfg, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &annotator{owners: owners, authors: aliases, severities: cfg.Severity}, nil
+}
+
+// annotate assigns owners, canonical authors and severities to the comments
+func (a *annotator) annotate(comments []todos.Comment) {
+	a.owners.assign(comments)
+	normalizeAuthors(a.authors, comments)
+	todos.AssignSeverities(comments, a.severities)
+}
+
+// annotateComments assigns owners, canonical authors and severities to the
+// comments from the project files for dir and the config
+func annotateComments(cfg *config.Config, dir string, comments []todos.Comment) error {
+	a, err := loadAnnotator(cfg, dir)
+	if err != nil {
+		return err
+	}
+
+	a.annotate(comments)
+	return nil
+}
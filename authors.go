@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/pkg/authors"
+	"github.com/euforic/todos/todos"
+)
+
+// loadAuthors builds the author alias map from the .mailmap and the
+// configured or first .todos-authors file found from dir upward, and the
+// config's authors, in increasing order of precedence. nil is returned if
+// there are no aliases.
+func loadAuthors(cfg *config.Config, dir string) (*authors.Map, error) {
+	m := authors.New()
+
+	mailmap, err := authors.Find(dir, authors.MailmapName)
+	if err != nil {
+		return nil, err
+	}
+
+	path := cfg.AuthorsFile
+	if path == "" {
+		if path, err = authors.Find(dir, authors.FileName); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range []string{mailmap, path} {
+		if file == "" {
+			continue
+		}
+
+		aliases, err := authors.Load(file)
+		if err != nil {
+			return nil, err
+		}
+		m.Merge(aliases)
+	}
+
+	configured := authors.New()
+	for canonical, aliases := range cfg.Authors {
+		configured.Add(canonical, aliases...)
+	}
+	m.Merge(configured)
+
+	if m.Len() == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// normalizeAuthors replaces the authors of the comments with their
+// canonical names
+func normalizeAuthors(m *authors.Map, comments []todos.Comment) {
+	if m == nil {
+		return
+	}
+
+	for i := range comments {
+		comments[i] = todos.MapAuthors(comments[i], m.Canonical)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+	"github.com/euforic/todos/tracker"
+)
+
+var checkCommand = &command{
+	name:    "check",
+	usage:   "check [options] [path...]",
+	summary: "Check comments against the configured policies and exit non-zero on violations",
+	run:     runCheck,
+}
+
+// runCheck runs the check command
+func runCheck(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	defineStdinFlags(fs, flags)
+	fs.IntVar(&flags.validateMax, "validate-max", 0, "Validate that the number of comments is less than or equal to the max")
+	fs.StringVar(&flags.failOn, "fail-on", "", "Exit with status 1 if any comment has this severity or higher (info, warning, error)")
+	output := fs.String("output", "text", "Output style of the violations (text, json)")
+	reportPath := fs.String("violations-report", "", "Also write the violations as JSON to this file")
+	issues := fs.Bool("issues", false, "Flag comments that refer to closed or missing issues in the configured tracker")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, comments, err := findComments(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	rules := append(append(append([]policy.Rule{}, cfg.Policies...), validateMaxRules(cfg)...), failOnRules(cfg)...)
+	if *issues {
+		rules = append(rules, policy.Rule{Name: "issues", RequireOpenIssue: true})
+	}
+
+	opts := policy.Options{}
+	for _, rule := range rules {
+		if rule.RequireOpenIssue && opts.Issue == nil {
+			t, err := tracker.New(cfg.Tracker)
+			if err != nil {
+				return err
+			}
+			opts.Issue = issueStates(t)
+		}
+	}
+
+	return checkPolicies(comments, rules, opts, *output, *reportPath)
+}
+
+// issueStates returns a policy.IssueFunc that looks up each issue in the
+// tracker once. The tracker states are the same as the policy ones.
+func issueStates(t tracker.Tracker) policy.IssueFunc {
+	states := map[string]string{}
+
+	return func(id string) (string, error) {
+		if state, ok := states[id]; ok {
+			return state, nil
+		}
+
+		state, err := tracker.State(t, id)
+		if err != nil {
+			return "", err
+		}
+
+		states[id] = state
+		return state, nil
+	}
+}
+
+// validateMaxRules returns the rule for the -validate-max limit, if set
+func validateMaxRules(cfg *config.Config) []policy.Rule {
+	if cfg.ValidateMax <= 0 {
+		return nil
+	}
+
+	limit := cfg.ValidateMax
+	return []policy.Rule{{Name: "validate-max", Max: &limit}}
+}
+
+// checkPolicies checks the comments against rules and writes the violations
+// in the given output style. Text output goes to stderr, JSON to stdout. An
+// exitError with the highest violation exit code is returned if any rule is
+// broken.
+func checkPolicies(comments []todos.Comment, rules []policy.Rule, opts policy.Options, output string, reportPath string) error {
+	violations, err := policy.Check(comments, rules, opts)
+	if err != nil {
+		return err
+	}
+
+	if reportPath != "" {
+		if err := writeViolationsReport(reportPath, violations); err != nil {
+			return err
+		}
+	}
+
+	switch output {
+	case "json":
+		err = policy.WriteJSON(os.Stdout, violations)
+	case "text":
+		err = policy.WriteText(os.Stderr, violations)
+	default:
+		return fmt.Errorf("unknown output style %q", output)
+	}
+	if err != nil {
+		return err
+	}
+
+	if code := policy.ExitCode(violations); code != 0 {
+		return &exitError{code: code}
+	}
+
+	return nil
+}
+
+// writeViolationsReport writes the violations as JSON to path, or to stdout if path is "-"
+func writeViolationsReport(path string, violations []policy.Violation) error {
+	if path == "-" {
+		return policy.WriteJSON(os.Stdout, violations)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := policy.WriteJSON(file, violations); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,168 @@
+// Package config loads todos project configuration files.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+	"github.com/euforic/todos/tracker"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the config file names searched for, in order of precedence.
+var FileNames = []string{".todos.yml", ".todos.yaml", ".todos.toml", ".todos.json"}
+
+var (
+	errUnknownFormat = errors.New("unknown config format")
+)
+
+// Config holds the settings that can be set in a config file or with flags.
+type Config struct {
+	Types       []string `json:"types"`
+	Ignore      []string `json:"ignore"`
+	Hidden      bool     `json:"hidden"`
+	Permissive  bool     `json:"permissive"`
+	NoGitignore bool     `json:"no_gitignore"`
+	Output      string   `json:"output"`
+	Format      string   `json:"format"`
+	SortBy      string   `json:"sortby"`
+	GroupBy     string   `json:"groupby"`
+	ValidateMax int      `json:"validate_max"`
+	FailOn      string   `json:"fail_on"`
+	Cache       string   `json:"cache"`
+	Filter      string   `json:"filter"`
+	CodeOwners  string   `json:"codeowners"`
+	AuthorsFile string   `json:"authors_file"`
+
+	// Authors maps canonical author names to their aliases.
+	Authors map[string][]string `json:"authors"`
+
+	// These control how comments are matched, see todos.MatchOptions.
+	CaseSensitive  bool                `json:"case_sensitive"`
+	TypeAliases    map[string][]string `json:"type_aliases"`
+	MinTextLength  int                 `json:"min_text_length"`
+	RequireComment bool                `json:"require_comment"`
+
+	// Severity maps comment types to their severity: info, warning or error.
+	// Types not listed use todos.DefaultSeverities.
+	Severity map[string]string `json:"severity"`
+
+	// Patterns find comments in formats the types don't cover.
+	Patterns []todos.Pattern `json:"patterns"`
+
+	Policies []policy.Rule  `json:"policies"`
+	Tracker  tracker.Config `json:"tracker"`
+}
+
+// Default returns the configuration used when no config file or flags are given.
+func Default() *Config {
+	return &Config{
+		Types:       []string{"TODO", "FIXME"},
+		Ignore:      []string{},
+		Output:      "table",
+		Policies:    []policy.Rule{},
+		Authors:     map[string][]string{},
+		TypeAliases: map[string][]string{},
+		Patterns:    []todos.Pattern{},
+		Severity:    map[string]string{},
+	}
+}
+
+// Find walks up from dir looking for a config file and returns its path.
+// An empty path is returned if no config file is found.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the config file at path on top of the default configuration.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if err := cfg.LoadFile(path); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadFile reads the config file at path, overwriting any values it sets.
+// The format is chosen by the file extension.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.decode(data, filepath.Ext(path)); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// decode decodes data in the format given by ext into c.
+func (c *Config) decode(data []byte, ext string) error {
+	var values map[string]interface{}
+	var err error
+
+	switch strings.ToLower(ext) {
+	case ".json":
+		return strictUnmarshal(data, c)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		_, err = toml.Decode(string(data), &values)
+	default:
+		return fmt.Errorf("%w: %q", errUnknownFormat, ext)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Round trip through JSON so all formats share the same field names
+	// and type checking.
+	data, err = json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	return strictUnmarshal(data, c)
+}
+
+// strictUnmarshal decodes JSON into v, rejecting unknown fields so typos in
+// config files are reported instead of silently ignored.
+func strictUnmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
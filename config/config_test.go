@@ -0,0 +1,285 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/euforic/todos/pkg/duration"
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+	"github.com/euforic/todos/tracker"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecode(t *testing.T) {
+	want := &Config{
+		Types:       []string{"TODO", "FIXME", "HACK"},
+		Ignore:      []string{"vendor/", "*.min.js"},
+		Hidden:      true,
+		Permissive:  false,
+		NoGitignore: false,
+		Output:      "json",
+		Format:      "{{range .}}{{.File}} # {{.Line}}\n{{end}}\n",
+		SortBy:      "author:desc",
+		ValidateMax: 20,
+		Policies:    []policy.Rule{},
+		Authors:     map[string][]string{},
+		TypeAliases: map[string][]string{},
+		Patterns:    []todos.Pattern{},
+		Severity:    map[string]string{},
+	}
+
+	tests := []struct {
+		name    string
+		ext     string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "YAML",
+			ext:  ".yml",
+			data: `# project settings
+types: [TODO, FIXME, "HACK"]
+ignore:
+  - vendor/
+  - '*.min.js' # minified files
+hidden: true
+output: json
+format: |
+  {{range .}}{{.File}} # {{.Line}}
+  {{end}}
+sortby: "author:desc"
+validate_max: 20
+`,
+		},
+		{
+			name: "YAMLSequenceAtKeyIndent",
+			ext:  ".yaml",
+			data: `types:
+- TODO
+- FIXME
+- HACK
+ignore: [vendor/, "*.min.js"]
+hidden: True
+output: 'json'
+format: "{{range .}}{{.File}} # {{.Line}}\n{{end}}\n"
+sortby: author:desc
+validate_max: 20
+`,
+		},
+		{
+			name:    "InvalidType",
+			ext:     ".yml",
+			data:    "hidden: sometimes\n",
+			wantErr: true,
+		},
+		{
+			name: "TOML",
+			ext:  ".toml",
+			data: `# project settings
+types = ["TODO", "FIXME", "HACK"]
+ignore = [
+  "vendor/",
+  '*.min.js', # minified files
+]
+hidden = true
+output = "json"
+format = """
+{{range .}}{{.File}} # {{.Line}}\n{{end}}
+"""
+sortby = "author:desc"
+validate_max = 20
+`,
+		},
+		{
+			name: "JSON",
+			ext:  ".json",
+			data: `{
+  "types": ["TODO", "FIXME", "HACK"],
+  "ignore": ["vendor/", "*.min.js"],
+  "hidden": true,
+  "output": "json",
+  "format": "{{range .}}{{.File}} # {{.Line}}\n{{end}}\n",
+  "sortby": "author:desc",
+  "validate_max": 20
+}`,
+		},
+		{
+			name:    "UnknownField",
+			ext:     ".yml",
+			data:    "typo: true\n",
+			wantErr: true,
+		},
+		{
+			name:    "UnknownFormat",
+			ext:     ".ini",
+			data:    "types = TODO\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Default()
+			err := got.decode([]byte(tt.data), tt.ext)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !cmp.Equal(got, want) {
+				t.Errorf("decode() \n%s", cmp.Diff(got, want))
+			}
+		})
+	}
+}
+
+func TestDecodePolicies(t *testing.T) {
+	data := `
+policies:
+  - name: no-fixme
+    types: [FIXME]
+    max: 0
+    exit_code: 2
+  - name: internal
+    paths: [internal/]
+    max: 50
+    require_author: true
+    max_age: 90d
+`
+
+	zero, fifty := 0, 50
+	want := []policy.Rule{
+		{Name: "no-fixme", Types: []string{"FIXME"}, Max: &zero, ExitCode: 2},
+		{Name: "internal", Paths: []string{"internal/"}, Max: &fifty, RequireAuthor: true, MaxAge: duration.Duration(90 * 24 * time.Hour)},
+	}
+
+	got := Default()
+	if err := got.decode([]byte(data), ".yml"); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+
+	if !cmp.Equal(got.Policies, want) {
+		t.Errorf("decode() \n%s", cmp.Diff(got.Policies, want))
+	}
+}
+
+func TestDecodeYAMLFeatures(t *testing.T) {
+	data := `
+policies:
+  - &strict
+    name: no-fixme
+    types: [FIXME]
+    max: 0
+  - <<: *strict
+    name: no-hack
+    types: [HACK]
+severity: {TODO: info, "HACK": error}
+format: >-
+  {{.File}}
+  # not a comment
+`
+
+	zero := 0
+	want := Default()
+	want.Policies = []policy.Rule{
+		{Name: "no-fixme", Types: []string{"FIXME"}, Max: &zero},
+		{Name: "no-hack", Types: []string{"HACK"}, Max: &zero},
+	}
+	want.Severity = map[string]string{"TODO": "info", "HACK": "error"}
+	want.Format = "{{.File}} # not a comment"
+
+	got := Default()
+	if err := got.decode([]byte(data), ".yml"); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("decode() \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestDecodeTOMLTables(t *testing.T) {
+	data := `
+tracker.type = "file"
+tracker.path = "issues.json"
+
+[severity]
+TODO = "info"
+
+[[policies]]
+name = "no-fixme"
+types = ["FIXME"]
+max = 0
+
+[[policies]]
+name = "internal"
+paths = ["internal/"]
+max_age = "90d"
+`
+
+	zero := 0
+	want := Default()
+	want.Tracker = tracker.Config{Type: "file", Path: "issues.json"}
+	want.Severity = map[string]string{"TODO": "info"}
+	want.Policies = []policy.Rule{
+		{Name: "no-fixme", Types: []string{"FIXME"}, Max: &zero},
+		{Name: "internal", Paths: []string{"internal/"}, MaxAge: duration.Duration(90 * 24 * time.Hour)},
+	}
+
+	got := Default()
+	if err := got.decode([]byte(data), ".toml"); err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("decode() \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(root, ".todos.toml")
+	if err := os.WriteFile(path, []byte("types = [\"TODO\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Find(nested)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != path {
+		t.Errorf("Find() = %q, want %q", got, path)
+	}
+
+	// A closer config file takes precedence.
+	closer := filepath.Join(root, "a", ".todos.yml")
+	if err := os.WriteFile(closer, []byte("types: [FIXME]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = Find(nested)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got != closer {
+		t.Errorf("Find() = %q, want %q", got, closer)
+	}
+
+	cfg, err := Load(got)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cmp.Equal(cfg.Types, []string{"FIXME"}) || cfg.Output != "table" {
+		t.Errorf("Load() = %+v", cfg)
+	}
+}
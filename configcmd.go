@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+var configCommand = &command{
+	name:    "config",
+	usage:   "config print [options] [dir]",
+	summary: "Print the effective configuration after merging the config file and flags",
+	run:     runConfig,
+}
+
+var errConfigSubcommand = errors.New("expected 'config print'")
+
+// runConfig runs the config command
+func runConfig(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	defineOutputFlags(fs, flags)
+	fs.IntVar(&flags.validateMax, "validate-max", 0, "Validate that the number of comments is less than or equal to the max")
+	fs.StringVar(&flags.failOn, "fail-on", "", "Exit with status 1 if any comment has this severity or higher (info, warning, error)")
+
+	if len(args) == 0 || args[0] != "print" {
+		if err := parseFlags(fs, args); err != nil {
+			return err
+		}
+		fs.Usage()
+		return errConfigSubcommand
+	}
+
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(fs, flags, searchDir(fs))
+	if err != nil {
+		return err
+	}
+
+	// Filter expressions use && which is escaped by default.
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
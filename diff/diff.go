@@ -0,0 +1,219 @@
+// Package diff compares two sets of comments, such as the results of two
+// scans, and reports which comments were added, removed, moved or modified.
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/euforic/todos/todos"
+)
+
+// Kinds of changes.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Moved    = "moved"
+	Modified = "modified"
+)
+
+// similarity is the minimum share of words two texts must have in common
+// for a comment to be reported as modified rather than removed and added.
+const similarity = 0.5
+
+// Change is a difference between the old and new comments. Old is nil for
+// added comments and New is nil for removed comments.
+type Change struct {
+	Kind        string         `json:"kind"`
+	Fingerprint string         `json:"fingerprint"`
+	Old         *todos.Comment `json:"old,omitempty"`
+	New         *todos.Comment `json:"new,omitempty"`
+}
+
+// Result is the outcome of a comparison.
+type Result struct {
+	Added    []Change `json:"added"`
+	Removed  []Change `json:"removed"`
+	Moved    []Change `json:"moved"`
+	Modified []Change `json:"modified"`
+	// Unchanged is the number of comments found in the same file in both,
+	// possibly on another line.
+	Unchanged int `json:"unchanged"`
+}
+
+// Empty reports whether there are no changes.
+func (r Result) Empty() bool {
+	return len(r.Added)+len(r.Removed)+len(r.Moved)+len(r.Modified) == 0
+}
+
+// Fingerprint identifies a comment by its content: the type, author and
+// text with whitespace and case normalized. It does not depend on the file
+// or line, so it stays the same when code around the comment changes.
+func Fingerprint(c todos.Comment) string {
+	text := strings.ToLower(strings.Join(strings.Fields(c.Text), " "))
+	sum := sha256.Sum256([]byte(strings.ToUpper(c.Type) + "\x00" + strings.ToLower(c.Author) + "\x00" + text))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// Compare matches the old comments with the new ones. Comments with the
+// same fingerprint in the same file are unchanged, even if their line
+// changed. Remaining comments with the same fingerprint in another file were
+// moved. Remaining comments in the same file on the same line or with
+// similar text were modified. Everything else was added or removed.
+func Compare(oldComments, newComments []todos.Comment) Result {
+	olds, news := entries(oldComments), entries(newComments)
+	result := Result{Added: []Change{}, Removed: []Change{}, Moved: []Change{}, Modified: []Change{}}
+
+	// Unchanged: same content in the same file, matched in line order.
+	match(olds, news, func(o, n *entry) bool {
+		return o.fingerprint == n.fingerprint && o.comment.File == n.comment.File
+	}, func(o, n *entry) {
+		result.Unchanged++
+	})
+
+	match(olds, news, func(o, n *entry) bool {
+		return o.fingerprint == n.fingerprint
+	}, func(o, n *entry) {
+		result.Moved = append(result.Moved, change(Moved, n.fingerprint, o, n))
+	})
+
+	matchModified(olds, news, func(o, n *entry) {
+		result.Modified = append(result.Modified, change(Modified, n.fingerprint, o, n))
+	})
+	sort.SliceStable(result.Modified, func(i, j int) bool {
+		a, b := result.Modified[i].New, result.Modified[j].New
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	for _, o := range olds {
+		if !o.matched {
+			result.Removed = append(result.Removed, change(Removed, o.fingerprint, o, nil))
+		}
+	}
+	for _, n := range news {
+		if !n.matched {
+			result.Added = append(result.Added, change(Added, n.fingerprint, nil, n))
+		}
+	}
+
+	return result
+}
+
+type entry struct {
+	comment     todos.Comment
+	fingerprint string
+	words       map[string]bool
+	matched     bool
+}
+
+// entries returns the comments ordered by file and line with their fingerprints.
+func entries(comments []todos.Comment) []*entry {
+	sorted := append([]todos.Comment{}, comments...)
+	todos.SortComments(sorted, nil)
+
+	result := make([]*entry, len(sorted))
+	for i, c := range sorted {
+		result[i] = &entry{comment: c, fingerprint: Fingerprint(c), words: words(c.Text)}
+	}
+	return result
+}
+
+func change(kind, fingerprint string, o, n *entry) Change {
+	c := Change{Kind: kind, Fingerprint: fingerprint}
+	if o != nil {
+		old := o.comment
+		c.Old = &old
+	}
+	if n != nil {
+		comment := n.comment
+		c.New = &comment
+	}
+	return c
+}
+
+// match pairs each unmatched old entry with the first unmatched new entry
+// that satisfies same.
+func match(olds, news []*entry, same func(o, n *entry) bool, found func(o, n *entry)) {
+	for _, o := range olds {
+		if o.matched {
+			continue
+		}
+		for _, n := range news {
+			if !n.matched && same(o, n) {
+				o.matched, n.matched = true, true
+				found(o, n)
+				break
+			}
+		}
+	}
+}
+
+// matchModified pairs the remaining entries in the same file that are on the
+// same line with the same type or have similar text, best matches first.
+func matchModified(olds, news []*entry, found func(o, n *entry)) {
+	type candidate struct {
+		o, n  *entry
+		score float64
+	}
+
+	candidates := []candidate{}
+	for _, o := range olds {
+		if o.matched {
+			continue
+		}
+		for _, n := range news {
+			if n.matched || o.comment.File != n.comment.File {
+				continue
+			}
+
+			score := jaccard(o.words, n.words)
+			sameLine := o.comment.Line == n.comment.Line && strings.EqualFold(o.comment.Type, n.comment.Type)
+			if sameLine {
+				score++
+			}
+			if score >= similarity {
+				candidates = append(candidates, candidate{o: o, n: n, score: score})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	for _, c := range candidates {
+		if c.o.matched || c.n.matched {
+			continue
+		}
+		c.o.matched, c.n.matched = true, true
+		found(c.o, c.n)
+	}
+}
+
+func words(text string) map[string]bool {
+	result := map[string]bool{}
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		result[w] = true
+	}
+	return result
+}
+
+// jaccard returns the share of words in either set that are in both.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	both := 0
+	for w := range a {
+		if b[w] {
+			both++
+		}
+	}
+	return float64(both) / float64(len(a)+len(b)-both)
+}
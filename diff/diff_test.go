@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFingerprint(t *testing.T) {
+	a := todos.Comment{File: "a.go", Line: 1, Type: "TODO", Author: "Bob", Text: "fix  the parser"}
+	b := todos.Comment{File: "b.go", Line: 9, Type: "todo", Author: "bob", Text: "Fix the parser"}
+	c := todos.Comment{File: "a.go", Line: 1, Type: "FIXME", Author: "bob", Text: "fix the parser"}
+
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Errorf("Fingerprint() differs for the same content: %s != %s", Fingerprint(a), Fingerprint(b))
+	}
+	if Fingerprint(a) == Fingerprint(c) {
+		t.Errorf("Fingerprint() is the same for another type: %s", Fingerprint(a))
+	}
+}
+
+// summarize describes each change as "kind old -> new" with locations.
+func summarize(r Result) []string {
+	where := func(c *todos.Comment) string {
+		if c == nil {
+			return "_"
+		}
+		return location(c)
+	}
+
+	result := []string{}
+	for _, changes := range [][]Change{r.Added, r.Removed, r.Moved, r.Modified} {
+		for _, c := range changes {
+			result = append(result, c.Kind+" "+where(c.Old)+" -> "+where(c.New))
+		}
+	}
+	return result
+}
+
+func TestCompare(t *testing.T) {
+	todo := func(file string, line int, text string) todos.Comment {
+		return todos.Comment{File: file, Line: line, Type: "TODO", Text: text}
+	}
+
+	tests := []struct {
+		name          string
+		old, new      []todos.Comment
+		want          []string
+		wantUnchanged int
+	}{
+		{
+			name:          "LineShift",
+			old:           []todos.Comment{todo("a.go", 1, "one"), todo("a.go", 5, "two")},
+			new:           []todos.Comment{todo("a.go", 3, "one"), todo("a.go", 8, "two")},
+			want:          []string{},
+			wantUnchanged: 2,
+		},
+		{
+			name: "AddedRemoved",
+			old:  []todos.Comment{todo("a.go", 1, "one"), todo("a.go", 5, "gone")},
+			new:  []todos.Comment{todo("a.go", 1, "one"), todo("b.go", 2, "brand new")},
+			want: []string{
+				"added _ -> b.go:2",
+				"removed a.go:5 -> _",
+			},
+			wantUnchanged: 1,
+		},
+		{
+			name: "Moved",
+			old:  []todos.Comment{todo("a.go", 1, "one"), todo("a.go", 2, "one")},
+			new:  []todos.Comment{todo("a.go", 4, "one"), todo("b.go", 7, "one")},
+			want: []string{
+				"moved a.go:2 -> b.go:7",
+			},
+			wantUnchanged: 1,
+		},
+		{
+			name: "Modified",
+			old:  []todos.Comment{todo("a.go", 1, "fix the parser"), todo("a.go", 9, "old wording")},
+			new:  []todos.Comment{todo("a.go", 2, "fix the lexer and parser"), todo("a.go", 9, "entirely different")},
+			want: []string{
+				"modified a.go:1 -> a.go:2",
+				"modified a.go:9 -> a.go:9",
+			},
+		},
+		{
+			name: "DissimilarElsewhere",
+			old:  []todos.Comment{todo("a.go", 1, "fix the parser")},
+			new:  []todos.Comment{todo("a.go", 20, "write docs")},
+			want: []string{
+				"added _ -> a.go:20",
+				"removed a.go:1 -> _",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(tt.old, tt.new)
+			if diff := cmp.Diff(tt.want, summarize(got)); diff != "" {
+				t.Errorf("Compare() mismatch (-want +got):\n%s", diff)
+			}
+			if got.Unchanged != tt.wantUnchanged {
+				t.Errorf("Compare() unchanged = %d, want %d", got.Unchanged, tt.wantUnchanged)
+			}
+		})
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	result := Compare(
+		[]todos.Comment{{File: "a.go", Line: 1, Type: "TODO", Text: "gone"}},
+		[]todos.Comment{{File: "a.go", Line: 3, Type: "FIXME", Author: "bob", Authors: []string{"bob", "carol"}, Text: "a | b"}},
+	)
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"1 added, 1 removed, 0 moved, 0 modified, 0 unchanged",
+		"#### Added (1)",
+		"| FIXME | bob, carol | a.go:3 | a \\| b |",
+		"#### Removed (1)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteMarkdown() missing %q in:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Moved") {
+		t.Errorf("WriteMarkdown() has an empty section:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/euforic/todos/todos"
+)
+
+// summary is the counts line shared by the text and markdown output.
+func summary(r Result) string {
+	return fmt.Sprintf("%d added, %d removed, %d moved, %d modified, %d unchanged",
+		len(r.Added), len(r.Removed), len(r.Moved), len(r.Modified), r.Unchanged)
+}
+
+func location(c *todos.Comment) string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+func label(c *todos.Comment) string {
+	if authors := todos.AuthorList(*c); authors != "" {
+		return c.Type + "(" + authors + ")"
+	}
+	return c.Type
+}
+
+// WriteText writes the changes one per line, prefixed with + for added, -
+// for removed, > for moved and ~ for modified comments, and a summary.
+func WriteText(w io.Writer, r Result) error {
+	tabW := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, c := range r.Added {
+		fmt.Fprintf(tabW, "+\t%s\t%s:\t%s\n", location(c.New), label(c.New), c.New.Text)
+	}
+	for _, c := range r.Removed {
+		fmt.Fprintf(tabW, "-\t%s\t%s:\t%s\n", location(c.Old), label(c.Old), c.Old.Text)
+	}
+	for _, c := range r.Moved {
+		fmt.Fprintf(tabW, ">\t%s\t%s:\t%s (from %s)\n", location(c.New), label(c.New), c.New.Text, location(c.Old))
+	}
+	for _, c := range r.Modified {
+		fmt.Fprintf(tabW, "~\t%s\t%s:\t%s (was %s: %s)\n", location(c.New), label(c.New), c.New.Text, label(c.Old), c.Old.Text)
+	}
+
+	if !r.Empty() {
+		fmt.Fprintln(tabW)
+	}
+	fmt.Fprintln(tabW, summary(r))
+
+	return tabW.Flush()
+}
+
+// WriteMarkdown writes a summary and a table for each kind of change,
+// suitable for a pull request comment.
+func WriteMarkdown(w io.Writer, r Result) error {
+	fmt.Fprintf(w, "### TODO changes\n\n%s\n", summary(r))
+
+	if len(r.Added) > 0 {
+		writeMarkdownTable(w, "Added", r.Added, func(c Change) *todos.Comment { return c.New })
+	}
+	if len(r.Removed) > 0 {
+		writeMarkdownTable(w, "Removed", r.Removed, func(c Change) *todos.Comment { return c.Old })
+	}
+
+	if len(r.Moved) > 0 {
+		fmt.Fprintf(w, "\n#### Moved (%d)\n\n", len(r.Moved))
+		fmt.Fprintln(w, "| Type | Author | From | To | Text |")
+		fmt.Fprintln(w, "| --- | --- | --- | --- | --- |")
+		for _, c := range r.Moved {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n", c.New.Type, todos.EscapeMarkdown(todos.AuthorList(*c.New)), todos.EscapeMarkdown(location(c.Old)), todos.EscapeMarkdown(location(c.New)), todos.EscapeMarkdown(c.New.Text))
+		}
+	}
+
+	if len(r.Modified) > 0 {
+		fmt.Fprintf(w, "\n#### Modified (%d)\n\n", len(r.Modified))
+		fmt.Fprintln(w, "| File:Line | Before | After |")
+		fmt.Fprintln(w, "| --- | --- | --- |")
+		for _, c := range r.Modified {
+			fmt.Fprintf(w, "| %s | %s: %s | %s: %s |\n", todos.EscapeMarkdown(location(c.New)), todos.EscapeMarkdown(label(c.Old)), todos.EscapeMarkdown(c.Old.Text), todos.EscapeMarkdown(label(c.New)), todos.EscapeMarkdown(c.New.Text))
+		}
+	}
+
+	return nil
+}
+
+func writeMarkdownTable(w io.Writer, title string, changes []Change, comment func(Change) *todos.Comment) {
+	fmt.Fprintf(w, "\n#### %s (%d)\n\n", title, len(changes))
+	fmt.Fprintln(w, "| Type | Author | File:Line | Text |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, c := range changes {
+		cm := comment(c)
+		fmt.Fprintf(w, "| %s | %s | %s | %s |\n", cm.Type, todos.EscapeMarkdown(todos.AuthorList(*cm)), todos.EscapeMarkdown(location(cm)), todos.EscapeMarkdown(cm.Text))
+	}
+}
+
+// WriteJSON writes the result as JSON.
+func WriteJSON(w io.Writer, r Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
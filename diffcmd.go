@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/diff"
+	"github.com/euforic/todos/history"
+	"github.com/euforic/todos/todos"
+)
+
+var diffCommand = &command{
+	name:    "diff",
+	usage:   "diff [options] <old.json> <new.json> | -rev A [-rev B]",
+	summary: "Compare two scans and report added, removed, moved and modified comments",
+	run:     runDiff,
+}
+
+var errDiffSources = errors.New("diff compares two JSON files or revisions, or one revision with the working tree")
+
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runDiff runs the diff command
+func runDiff(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	revs := &stringList{}
+	fs.Var(revs, "rev", "Git revision to scan instead of a JSON file; repeat for the new side, or give once to compare with the working tree")
+	output := fs.String("output", "text", "Output style (text, md, json)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sources := len(*revs) + fs.NArg()
+	if flags.filesFrom != "" || (sources != 2 && !(len(*revs) == 1 && fs.NArg() == 0)) {
+		// Like flag errors, invalid sources are a usage error.
+		fmt.Fprintf(fs.Output(), "Error: %s\n", errDiffSources)
+		fs.Usage()
+		return &exitError{code: 2}
+	}
+
+	cfg, err := loadConfig(fs, flags, ".")
+	if err != nil {
+		return err
+	}
+
+	sides := [][]todos.Comment{}
+	for _, rev := range *revs {
+		comments, err := scanRev(cfg, rev)
+		if err != nil {
+			return err
+		}
+		sides = append(sides, comments)
+	}
+	for _, path := range fs.Args() {
+		comments, err := readComments(path)
+		if err != nil {
+			return err
+		}
+		sides = append(sides, comments)
+	}
+	if len(sides) == 1 {
+		comments, err := searchComments(cfg, []string{"."})
+		if err != nil {
+			return err
+		}
+		sides = append(sides, comments)
+	}
+
+	for i := range sides {
+		if sides[i], err = filterComments(cfg, sides[i]); err != nil {
+			return err
+		}
+	}
+
+	result := diff.Compare(sides[0], sides[1])
+
+	switch *output {
+	case "json":
+		return diff.WriteJSON(os.Stdout, result)
+	case "md", "markdown":
+		return diff.WriteMarkdown(os.Stdout, result)
+	case "text":
+		return diff.WriteText(os.Stdout, result)
+	default:
+		return fmt.Errorf("unknown output style %q (text, md, json)", *output)
+	}
+}
+
+// scanRev returns the comments in the tree of rev below the current
+// directory, without checking it out
+func scanRev(cfg *config.Config, rev string) ([]todos.Comment, error) {
+	ignoreList, err := buildIgnoreList(cfg, []string{"."})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := history.Scan(history.Options{
+		Dir:     ".",
+		Matcher: m,
+		Ignores: ignoreList,
+	}, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, annotateComments(cfg, ".", comments)
+}
@@ -0,0 +1,240 @@
+// Package filter implements a small expression language for selecting comments.
+//
+// An expression compares comment fields with literals and combines the
+// comparisons with boolean operators:
+//
+//	type == "FIXME" && author != "" && file =~ "^internal/"
+//	!(line < 100) || text =~ "(?i)security"
+//
+// The comparison operators are ==, !=, <, <=, >, >= and =~ and !~, which
+// match a regular expression. A field on its own is true if it is not empty.
+// Fields holding several values, such as authors and owner, support ==, =~
+// and their negations: owner == "@team" is true if any owner is @team and
+// owner != "@team" if none is.
+// Comparisons are combined with !, && and || and grouped with parentheses.
+// Strings are double quoted with Go escapes, or raw between backquotes.
+package filter
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/euforic/todos/todos"
+)
+
+var errSyntax = errors.New("filter syntax error")
+
+// kind is the type of a field.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindList
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindInt:
+		return "number"
+	case kindList:
+		return "list"
+	}
+	return "string"
+}
+
+// field is a comment field that can be used in an expression.
+type field struct {
+	kind kind
+	str  func(c *todos.Comment) string
+	num  func(c *todos.Comment) int
+	list func(c *todos.Comment) []string
+}
+
+// fields are the comment fields available in expressions, by name.
+var fields = map[string]field{
+	"file":     {kind: kindString, str: func(c *todos.Comment) string { return c.File }},
+	"line":     {kind: kindInt, num: func(c *todos.Comment) int { return c.Line }},
+	"type":     {kind: kindString, str: func(c *todos.Comment) string { return c.Type }},
+	"text":     {kind: kindString, str: func(c *todos.Comment) string { return c.Text }},
+	"author":   {kind: kindString, str: func(c *todos.Comment) string { return c.Author }},
+	"authors":  {kind: kindList, list: func(c *todos.Comment) []string { return todos.Authors(*c) }},
+	"issue":    {kind: kindString, str: func(c *todos.Comment) string { return c.Issue }},
+	"language": {kind: kindString, str: func(c *todos.Comment) string { return c.Language }},
+	"owner":    {kind: kindList, list: func(c *todos.Comment) []string { return c.Owners }},
+	"severity": {kind: kindString, str: func(c *todos.Comment) string { return todos.SeverityOf(*c) }},
+}
+
+// Fields returns the names of the fields that can be used in expressions.
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filter is a compiled filter expression.
+type Filter struct {
+	expr string
+	root node
+}
+
+// Parse compiles an expression. An empty expression matches every comment.
+func Parse(expr string) (*Filter, error) {
+	f := &Filter{expr: expr}
+	if strings.TrimSpace(expr) == "" {
+		return f, nil
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+
+	f.root = root
+	return f, nil
+}
+
+// String returns the expression the filter was parsed from.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether the comment matches the filter.
+func (f *Filter) Match(c todos.Comment) bool {
+	if f.root == nil {
+		return true
+	}
+	return f.root.eval(&c)
+}
+
+// Apply returns the comments that match the filter.
+func (f *Filter) Apply(comments []todos.Comment) []todos.Comment {
+	if f.root == nil {
+		return comments
+	}
+
+	result := []todos.Comment{}
+	for _, c := range comments {
+		if f.root.eval(&c) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// node is a node of a compiled expression.
+type node interface {
+	eval(c *todos.Comment) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(c *todos.Comment) bool { return n.left.eval(c) && n.right.eval(c) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(c *todos.Comment) bool { return n.left.eval(c) || n.right.eval(c) }
+
+type notNode struct{ expr node }
+
+func (n notNode) eval(c *todos.Comment) bool { return !n.expr.eval(c) }
+
+// truthyNode is a field on its own, true if the field is not empty or zero.
+type truthyNode struct{ field field }
+
+func (n truthyNode) eval(c *todos.Comment) bool {
+	switch n.field.kind {
+	case kindInt:
+		return n.field.num(c) != 0
+	case kindList:
+		return len(n.field.list(c)) > 0
+	}
+	return n.field.str(c) != ""
+}
+
+type stringNode struct {
+	field field
+	op    string
+	value string
+}
+
+func (n stringNode) eval(c *todos.Comment) bool {
+	if n.field.kind == kindList {
+		return anyMatch(n.field.list(c), func(s string) bool { return s == n.value }) == (n.op == "==")
+	}
+	return compare(strings.Compare(n.field.str(c), n.value), n.op)
+}
+
+type intNode struct {
+	field field
+	op    string
+	value int
+}
+
+func (n intNode) eval(c *todos.Comment) bool {
+	v := n.field.num(c)
+	switch {
+	case v < n.value:
+		return compare(-1, n.op)
+	case v > n.value:
+		return compare(1, n.op)
+	default:
+		return compare(0, n.op)
+	}
+}
+
+type regexpNode struct {
+	field  field
+	negate bool
+	re     *regexp.Regexp
+}
+
+func (n regexpNode) eval(c *todos.Comment) bool {
+	if n.field.kind == kindList {
+		return anyMatch(n.field.list(c), n.re.MatchString) != n.negate
+	}
+	return n.re.MatchString(n.field.str(c)) != n.negate
+}
+
+// anyMatch reports whether match is true for any of the values.
+func anyMatch(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare reports whether a comparison with the result cmp satisfies op.
+func compare(cmp int, op string) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilter(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "internal/db.go", Line: 4, Type: "FIXME", Text: "connection leak", Author: "alice", Issue: "#12", Owners: []string{"@acme/db", "@carol"}},
+		{File: "cmd/main.go", Line: 120, Type: "TODO", Text: "parse flags", Owners: []string{"@acme/cli"}},
+		{File: "internal/api.go", Line: 9, Type: "TODO", Text: "Security review", Author: "bob", Authors: []string{"bob", "carol"}},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want []int
+	}{
+		{name: "Empty", expr: "", want: []int{0, 1, 2}},
+		{name: "Equal", expr: `type == "FIXME"`, want: []int{0}},
+		{name: "NotEqual", expr: `author != ""`, want: []int{0, 2}},
+		{name: "Regexp", expr: `file =~ "^internal/"`, want: []int{0, 2}},
+		{name: "NotRegexp", expr: "text !~ `(?i)security`", want: []int{0, 1}},
+		{name: "And", expr: `type == "TODO" && author != "" && file =~ "^internal/"`, want: []int{2}},
+		{name: "Or", expr: `type == "FIXME" || line >= 100`, want: []int{0, 1}},
+		{name: "Precedence", expr: `type == "FIXME" || type == "TODO" && author == "bob"`, want: []int{0, 2}},
+		{name: "Parens", expr: `(type == "FIXME" || type == "TODO") && author == "bob"`, want: []int{2}},
+		{name: "Not", expr: `!(line < 100)`, want: []int{1}},
+		{name: "Truthy", expr: `issue`, want: []int{0}},
+		{name: "NotTruthy", expr: `!author && line > 0`, want: []int{1}},
+		{name: "Escapes", expr: `text == "parse flags"`, want: []int{1}},
+		{name: "Whitespace", expr: "\tline<=9&&line>4 ", want: []int{2}},
+		{name: "ListEqual", expr: `owner == "@carol"`, want: []int{0}},
+		{name: "ListNotEqual", expr: `owner != "@carol"`, want: []int{1, 2}},
+		{name: "ListRegexp", expr: `owner =~ "^@acme/"`, want: []int{0, 1}},
+		{name: "ListNotRegexp", expr: `owner !~ "db"`, want: []int{1, 2}},
+		{name: "ListTruthy", expr: `!owner`, want: []int{2}},
+		{name: "Authors", expr: `authors == "carol"`, want: []int{2}},
+		{name: "AuthorsFallback", expr: `authors == "alice"`, want: []int{0}},
+		{name: "NoAuthors", expr: `!authors`, want: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+
+			want := []todos.Comment{}
+			for _, i := range tt.want {
+				want = append(want, comments[i])
+			}
+
+			if got := f.Apply(comments); !cmp.Equal(got, want) {
+				t.Errorf("Apply() \n%s", cmp.Diff(got, want))
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		`type ==`,
+		`type == FIXME`,
+		`assignee == "alice"`,
+		`owner < "@a"`,
+		`line == "4"`,
+		`line =~ "4"`,
+		`type == 4`,
+		`file =~ "("`,
+		`(type == "TODO"`,
+		`type == "TODO")`,
+		`type == "TODO" &&`,
+		`type = "TODO"`,
+		`text == "unterminated`,
+		`type == "TODO" author == "bob"`,
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); !errors.Is(err, errSyntax) {
+			t.Errorf("Parse(%q) error = %v, want %v", expr, err, errSyntax)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// operators are the operator tokens, longest first so that prefixes such as
+// "!" don't shadow "!=".
+var operators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!"}
+
+func lex(expr string) ([]token, error) {
+	tokens := []token{}
+
+	for pos := 0; pos < len(expr); {
+		r, size := utf8.DecodeRuneInString(expr[pos:])
+
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			pos++
+			continue
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			pos++
+			continue
+		case r == '"' || r == '`':
+			end := stringEnd(expr, pos)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string at offset %d", errSyntax, pos)
+			}
+			value, err := strconv.Unquote(expr[pos:end])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid string %s at offset %d", errSyntax, expr[pos:end], pos)
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: pos})
+			pos = end
+			continue
+		case r == '-' || unicode.IsDigit(r):
+			end := pos + 1
+			for end < len(expr) && expr[end] >= '0' && expr[end] <= '9' {
+				end++
+			}
+			if expr[pos:end] == "-" {
+				return nil, fmt.Errorf("%w: unexpected \"-\" at offset %d", errSyntax, pos)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: expr[pos:end], pos: pos})
+			pos = end
+			continue
+		case r == '_' || unicode.IsLetter(r):
+			end := pos
+			for end < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: expr[pos:end], pos: pos})
+			pos = end
+			continue
+		}
+
+		op := ""
+		for _, o := range operators {
+			if strings.HasPrefix(expr[pos:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("%w: unexpected %q at offset %d", errSyntax, r, pos)
+		}
+		tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+		pos += len(op)
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// stringEnd returns the offset after the string literal starting at pos, or
+// -1 if it is not terminated.
+func stringEnd(expr string, pos int) int {
+	quote := expr[pos]
+	for i := pos + 1; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// parser is a recursive descent parser for the grammar:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = field [ op literal ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at offset %d", errSyntax, fmt.Sprintf(format, args...), tok.pos)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for tok := p.peek(); tok.kind == tokOp && tok.text == "||"; tok = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for tok := p.peek(); tok.kind == tokOp && tok.text == "&&"; tok = p.peek() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.next()
+
+	switch {
+	case tok.kind == tokOp && tok.text == "!":
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{expr}, nil
+	case tok.kind == tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, p.errorf(end, "expected \")\", found %s", end)
+		}
+		return expr, nil
+	case tok.kind == tokIdent:
+		return p.parseComparison(tok)
+	}
+
+	return nil, p.errorf(tok, "expected field, found %s", tok)
+}
+
+func (p *parser) parseComparison(name token) (node, error) {
+	f, ok := fields[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown field %q (fields: %s)", name.text, strings.Join(Fields(), ", "))
+	}
+
+	op := p.peek()
+	if op.kind != tokOp || op.text == "&&" || op.text == "||" || op.text == "!" {
+		return truthyNode{field: f}, nil
+	}
+	p.next()
+
+	value := p.next()
+	switch {
+	case op.text == "=~" || op.text == "!~":
+		if f.kind == kindInt {
+			return nil, p.errorf(op, "%s can't be used with %s field %q", op.text, f.kind, name.text)
+		}
+		if value.kind != tokString {
+			return nil, p.errorf(value, "expected regular expression string, found %s", value)
+		}
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, p.errorf(value, "invalid regular expression: %v", err)
+		}
+		return regexpNode{field: f, negate: op.text == "!~", re: re}, nil
+
+	case f.kind == kindInt:
+		if value.kind != tokNumber {
+			return nil, p.errorf(value, "expected number to compare with field %q, found %s", name.text, value)
+		}
+		n, err := strconv.Atoi(value.text)
+		if err != nil {
+			return nil, p.errorf(value, "invalid number %s", value.text)
+		}
+		return intNode{field: f, op: op.text, value: n}, nil
+
+	default:
+		if f.kind == kindList && op.text != "==" && op.text != "!=" {
+			return nil, p.errorf(op, "%s can't be used with %s field %q", op.text, f.kind, name.text)
+		}
+		if value.kind != tokString {
+			return nil, p.errorf(value, "expected string to compare with field %q, found %s", name.text, value)
+		}
+		return stringNode{field: f, op: op.text, value: value.text}, nil
+	}
+}
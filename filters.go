@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/filter"
+	"github.com/euforic/todos/todos"
+)
+
+// defineFilterFlags defines the flags that select which of the found
+// comments are kept
+func defineFilterFlags(fs *flag.FlagSet, v *searchFlags) {
+	fs.StringVar(&v.filter, "filter", "", `Only keep comments matching an expression, e.g. 'type == "FIXME" && file =~ "^internal/"'`)
+	fs.StringVar(&v.filterTypes, "type", "", "Only keep comments of these comma-separated types")
+	fs.StringVar(&v.filterAuthors, "author", "", "Only keep comments by these comma-separated authors")
+	fs.StringVar(&v.filterPaths, "path", "", "Only keep comments in these comma-separated files and directories")
+	fs.StringVar(&v.textContains, "text-contains", "", "Only keep comments whose text contains this string, ignoring case")
+}
+
+// flagFilter returns the expression equivalent to the -type, -author, -path
+// and -text-contains flags, or an empty string if none are set
+func flagFilter(flags *searchFlags) string {
+	exprs := []string{}
+
+	if types := splitList(flags.filterTypes); len(types) > 0 {
+		exprs = append(exprs, "type =~ "+strconv.Quote("(?i)^(?:"+quoteList(types)+")$"))
+	}
+	if authors := splitList(flags.filterAuthors); len(authors) > 0 {
+		exprs = append(exprs, "authors =~ "+strconv.Quote("(?i)^(?:"+quoteList(authors)+")$"))
+	}
+	if paths := splitList(flags.filterPaths); len(paths) > 0 {
+		exprs = append(exprs, "file =~ "+strconv.Quote("^(?:\\./)?(?:"+pathList(paths)+")"))
+	}
+	if flags.textContains != "" {
+		exprs = append(exprs, "text =~ "+strconv.Quote("(?i)"+regexp.QuoteMeta(flags.textContains)))
+	}
+
+	return strings.Join(exprs, " && ")
+}
+
+// pathList returns an alternation of the paths that matches them and the
+// files below them, but not siblings that start with the same name, so
+// internal doesn't match internal_tools/
+func pathList(paths []string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		p = strings.TrimPrefix(p, "./")
+		quoted[i] = regexp.QuoteMeta(p)
+		if !strings.HasSuffix(p, "/") {
+			quoted[i] += "(?:/|$)"
+		}
+	}
+	return strings.Join(quoted, "|")
+}
+
+// quoteList returns an alternation of the literal values
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// andFilters combines filter expressions, skipping empty ones
+func andFilters(exprs ...string) string {
+	nonEmpty := []string{}
+	for _, expr := range exprs {
+		if strings.TrimSpace(expr) != "" {
+			nonEmpty = append(nonEmpty, expr)
+		}
+	}
+
+	if len(nonEmpty) == 1 {
+		return nonEmpty[0]
+	}
+	for i, expr := range nonEmpty {
+		nonEmpty[i] = "(" + expr + ")"
+	}
+	return strings.Join(nonEmpty, " && ")
+}
+
+// filterComments returns the comments matching the configured filter
+func filterComments(cfg *config.Config, comments []todos.Comment) ([]todos.Comment, error) {
+	f, err := filter.Parse(cfg.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Apply(comments), nil
+}
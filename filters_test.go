@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/euforic/todos/filter"
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFlagFilterPaths(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "internal/db.go"},
+		{File: "./internal/api/api.go"},
+		{File: "internal_tools/gen.go"},
+		{File: "main.go"},
+		{File: "main.go.orig"},
+	}
+
+	tests := []struct {
+		paths string
+		want  []string
+	}{
+		{paths: "internal", want: []string{"internal/db.go", "./internal/api/api.go"}},
+		{paths: "./internal/", want: []string{"internal/db.go", "./internal/api/api.go"}},
+		{paths: "internal/api", want: []string{"./internal/api/api.go"}},
+		{paths: "main.go,internal_tools", want: []string{"internal_tools/gen.go", "main.go"}},
+	}
+
+	for _, tt := range tests {
+		f, err := filter.Parse(flagFilter(&searchFlags{filterPaths: tt.paths}))
+		if err != nil {
+			t.Fatalf("-path %s: %v", tt.paths, err)
+		}
+
+		got := []string{}
+		for _, c := range f.Apply(comments) {
+			got = append(got, c.File)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("-path %s \n%s", tt.paths, cmp.Diff(got, tt.want))
+		}
+	}
+}
@@ -2,4 +2,8 @@ module github.com/euforic/todos
 
 go 1.19
 
-require github.com/google/go-cmp v0.5.9
+require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/google/go-cmp v0.5.9
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,219 @@
+// Package history counts comments across the git history of a repository.
+package history
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/euforic/todos/pkg/git"
+	"github.com/euforic/todos/todos"
+)
+
+var errStep = errors.New("step must be positive")
+
+// Point is the comment count at a point in time.
+type Point struct {
+	Time time.Time `json:"time"`
+	// Commit is the last commit before Time.
+	Commit string         `json:"commit"`
+	Total  int            `json:"total"`
+	ByType map[string]int `json:"by_type"`
+	// ByAuthor counts a comment once for each of its authors, and under
+	// todos.NoAuthor if it has none.
+	ByAuthor map[string]int `json:"by_author"`
+}
+
+// Options configures Collect.
+type Options struct {
+	// Dir is a directory in the repository. Only files below it are scanned.
+	Dir string
+	// Rev is the revision whose history is walked. Defaults to HEAD.
+	Rev string
+	// Since is how far back to go and Step the time between samples.
+	Since time.Duration
+	Step  time.Duration
+	// Now is the time of the last sample. Defaults to time.Now().
+	Now time.Time
+
+	// Types, Permissive and Ignores are used as in todos.SearchPaths, with
+	// ignores matched against paths relative to Dir.
+	Types      []string
+	Permissive bool
+	Ignores    []string
+	// Matcher finds the comments instead of Types and Permissive if set.
+	Matcher *todos.Matcher
+	// Author maps each of the authors of comments to a canonical name before
+	// comments are matched and counted. Authors are kept as written if nil.
+	Author func(string) string
+	// Match selects the comments that are counted. All are counted if nil.
+	Match func(todos.Comment) bool
+}
+
+// Collect samples the history of the repository every Step from Since ago
+// until Now, scanning the tree of the last commit before each sample without
+// checking it out. Samples before the first commit are left out. Files are
+// only parsed once for each distinct content.
+func Collect(opts Options) ([]Point, error) {
+	if opts.Step <= 0 {
+		return nil, errStep
+	}
+	if opts.Rev == "" {
+		opts.Rev = "HEAD"
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	c, err := newCollector(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []Point{}
+	for t := opts.Now.Add(-opts.Since); !t.After(opts.Now); t = t.Add(opts.Step) {
+		commit, err := git.RevBefore(opts.Dir, opts.Rev, t)
+		if err != nil {
+			return nil, err
+		}
+		if commit == "" {
+			continue
+		}
+
+		point, err := c.count(commit)
+		if err != nil {
+			return nil, err
+		}
+
+		point.Time = t
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// Scan returns the comments in the tree of rev below opts.Dir, without
+// checking it out. Since, Step and Now are not used.
+func Scan(opts Options, rev string) ([]todos.Comment, error) {
+	commit, err := git.ResolveRev(opts.Dir, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newCollector(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.comments(commit)
+}
+
+// blobKey identifies parsed content. The path matters because it selects
+// the language of the comments.
+type blobKey struct {
+	hash string
+	path string
+}
+
+type collector struct {
+	opts    Options
+	matcher *todos.Matcher
+	blobs   map[blobKey][]todos.Comment
+	points  map[string]Point
+}
+
+// newCollector returns a collector using opts.Matcher, or a matcher for
+// opts.Types and opts.Permissive.
+func newCollector(opts Options) (*collector, error) {
+	m := opts.Matcher
+	if m == nil {
+		var err error
+		m, err = todos.NewMatcher(todos.MatchOptions{Types: opts.Types, Permissive: opts.Permissive})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &collector{opts: opts, matcher: m, blobs: map[blobKey][]todos.Comment{}, points: map[string]Point{}}, nil
+}
+
+// count counts the comments in the tree of a commit.
+func (c *collector) count(commit string) (Point, error) {
+	if point, ok := c.points[commit]; ok {
+		return point, nil
+	}
+
+	comments, err := c.comments(commit)
+	if err != nil {
+		return Point{}, err
+	}
+
+	point := Point{Commit: commit, ByType: map[string]int{}, ByAuthor: map[string]int{}}
+	for _, comment := range comments {
+		point.Total++
+		point.ByType[comment.Type]++
+		for _, author := range todos.AuthorsOrNone(comment) {
+			point.ByAuthor[author]++
+		}
+	}
+
+	c.points[commit] = point
+	return point, nil
+}
+
+// comments returns the comments in the tree of a commit that match.
+func (c *collector) comments(commit string) ([]todos.Comment, error) {
+	entries, err := git.ListTree(c.opts.Dir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []blobKey{}
+	missing := []string{}
+	paths := map[string][]string{}
+
+	for _, entry := range entries {
+		if todos.IgnoredPath(entry.Path, c.opts.Ignores) {
+			continue
+		}
+
+		key := blobKey{hash: entry.Hash, path: entry.Path}
+		keys = append(keys, key)
+
+		if _, ok := c.blobs[key]; !ok {
+			if len(paths[entry.Hash]) == 0 {
+				missing = append(missing, entry.Hash)
+			}
+			paths[entry.Hash] = append(paths[entry.Hash], entry.Path)
+		}
+	}
+
+	err = git.ReadBlobs(c.opts.Dir, missing, func(hash string, data []byte) error {
+		for _, path := range paths[hash] {
+			comments, err := c.matcher.Parse(bytes.NewReader(data), path)
+			if err != nil {
+				// Content that can't be parsed, such as very long lines, has no comments.
+				comments = nil
+			}
+			c.blobs[blobKey{hash: hash, path: path}] = comments
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []todos.Comment{}
+	for _, key := range keys {
+		for _, comment := range c.blobs[key] {
+			if c.opts.Author != nil {
+				comment = todos.MapAuthors(comment, c.opts.Author)
+			}
+			if c.opts.Match != nil && !c.opts.Match(comment) {
+				continue
+			}
+			result = append(result, comment)
+		}
+	}
+
+	return result, nil
+}
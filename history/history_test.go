@@ -0,0 +1,185 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+// repo creates a git repository with a commit of the given files on each
+// date, in order.
+func repo(t *testing.T, commits []time.Time, files []map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(env []string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	git(nil, "init", "-q")
+	for i, date := range commits {
+		for name, content := range files[i] {
+			path := filepath.Join(dir, name)
+			if content == "" {
+				git(nil, "rm", "-q", name)
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			git(nil, "add", name)
+		}
+
+		stamp := date.Format(time.RFC3339)
+		git([]string{
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE=" + stamp,
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE=" + stamp,
+		}, "commit", "-q", "-m", "commit")
+	}
+
+	return dir
+}
+
+func TestCollect(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	dir := repo(t,
+		[]time.Time{now.Add(-25 * day), now.Add(-15 * day), now.Add(-5 * day)},
+		[]map[string]string{
+			{"a.go": "// TODO: one\n", "vendor/v.go": "// TODO: vendored\n"},
+			{"a.go": "// TODO: one\n// FIXME(alice): two\n", "b.go": "// TODO: three\n"},
+			{"b.go": ""},
+		},
+	)
+
+	points, err := Collect(Options{
+		Dir:     dir,
+		Since:   30 * day,
+		Step:    10 * day,
+		Now:     now,
+		Types:   []string{"TODO", "FIXME"},
+		Ignores: []string{"vendor/"},
+	})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	// The first sample is before the first commit.
+	got := []string{}
+	for _, p := range points {
+		got = append(got, p.Time.Format("01-02")+" "+strings.Repeat("T", p.ByType["TODO"])+strings.Repeat("F", p.ByType["FIXME"]))
+	}
+	want := []string{"02-10 T", "02-20 TTF", "03-01 TF"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Collect() \n%s", cmp.Diff(got, want))
+	}
+
+	if points[1].ByAuthor["alice"] != 1 || points[1].Total != 3 || points[1].Commit == points[0].Commit {
+		t.Errorf("Collect() point = %+v", points[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, points); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "time,commit,total,type:FIXME,type:TODO,author:(no author),author:alice\n2024-02-10T12:00:00Z,") {
+		t.Errorf("WriteCSV() = %s", buf.String())
+	}
+}
+
+func TestCollectAuthors(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	dir := repo(t,
+		[]time.Time{now.Add(-time.Hour)},
+		[]map[string]string{{"a.go": "// TODO(al, bob): one\n// TODO(alice): two\n// TODO: three\n"}},
+	)
+
+	aliases := map[string]string{"al": "alice"}
+	points, err := Collect(Options{
+		Dir:   dir,
+		Since: 24 * time.Hour,
+		Step:  24 * time.Hour,
+		Now:   now,
+		Types: []string{"TODO"},
+		Author: func(author string) string {
+			if canonical, ok := aliases[author]; ok {
+				return canonical
+			}
+			return author
+		},
+	})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	want := map[string]int{"alice": 2, "bob": 1, todos.NoAuthor: 1}
+	if len(points) != 1 || !cmp.Equal(points[0].ByAuthor, want) {
+		t.Errorf("Collect() points = %+v, want ByAuthor %v", points, want)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []int
+		want   string
+	}{
+		{values: nil, want: ""},
+		{values: []int{3, 3}, want: "▅▅"},
+		{values: []int{0, 7, 14}, want: "▁▄█"},
+		{values: []int{10, 2, 6}, want: "█▁▄"},
+	}
+
+	for _, tt := range tests {
+		if got := Sparkline(tt.values); got != tt.want {
+			t.Errorf("Sparkline(%v) = %s, want %s", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	dir := repo(t,
+		[]time.Time{now.Add(-2 * time.Hour), now.Add(-time.Hour)},
+		[]map[string]string{
+			{"a.go": "// TODO: one\n"},
+			{"a.go": "// TODO: one\n// FIXME: two\n"},
+		},
+	)
+
+	got := map[string]int{}
+	for _, rev := range []string{"HEAD~1", "HEAD"} {
+		comments, err := Scan(Options{Dir: dir, Types: []string{"TODO", "FIXME"}}, rev)
+		if err != nil {
+			t.Fatalf("Scan(%s) error = %v", rev, err)
+		}
+		got[rev] = len(comments)
+	}
+	if want := map[string]int{"HEAD~1": 1, "HEAD": 2}; !cmp.Equal(got, want) {
+		t.Errorf("Scan() \n%s", cmp.Diff(got, want))
+	}
+
+	if _, err := Scan(Options{Dir: dir}, "missing"); err == nil {
+		t.Error("Scan(missing) error = nil")
+	}
+}
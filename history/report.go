@@ -0,0 +1,163 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// sparks are the characters of a sparkline, from lowest to highest.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws the values as a line of block characters scaled between
+// the lowest and highest value.
+func Sparkline(values []int) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	low, high := values[0], values[0]
+	for _, v := range values {
+		if v < low {
+			low = v
+		}
+		if v > high {
+			high = v
+		}
+	}
+
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := len(sparks) / 2
+		if high > low {
+			level = (v - low) * (len(sparks) - 1) / (high - low)
+		}
+		line[i] = sparks[level]
+	}
+
+	return string(line)
+}
+
+// types returns the comment types counted in any point, in order.
+func types(points []Point) []string {
+	return keys(points, func(p Point) map[string]int { return p.ByType })
+}
+
+// authors returns the authors counted in any point, in order.
+func authors(points []Point) []string {
+	return keys(points, func(p Point) map[string]int { return p.ByAuthor })
+}
+
+func keys(points []Point, counts func(Point) map[string]int) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, p := range points {
+		for k := range counts(p) {
+			if !seen[k] {
+				seen[k] = true
+				result = append(result, k)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func series(points []Point, value func(Point) int) []int {
+	values := make([]int, len(points))
+	for i, p := range points {
+		values[i] = value(p)
+	}
+	return values
+}
+
+func shortHash(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// WriteTable writes the counts by type at each point as a table, followed by
+// a sparkline and the change of the total and of each type.
+func WriteTable(w io.Writer, points []Point) error {
+	tabW := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	typeNames := types(points)
+
+	fmt.Fprint(tabW, "Date\tCommit\tTotal")
+	for _, t := range typeNames {
+		fmt.Fprintf(tabW, "\t%s", t)
+	}
+	fmt.Fprintln(tabW)
+
+	for _, p := range points {
+		fmt.Fprintf(tabW, "%s\t%s\t%d", p.Time.Format("2006-01-02"), shortHash(p.Commit), p.Total)
+		for _, t := range typeNames {
+			fmt.Fprintf(tabW, "\t%d", p.ByType[t])
+		}
+		fmt.Fprintln(tabW)
+	}
+
+	if len(points) > 0 {
+		fmt.Fprintln(tabW)
+		writeTrend(tabW, "Total", series(points, func(p Point) int { return p.Total }))
+		for _, t := range typeNames {
+			t := t
+			writeTrend(tabW, t, series(points, func(p Point) int { return p.ByType[t] }))
+		}
+	}
+
+	return tabW.Flush()
+}
+
+func writeTrend(w io.Writer, name string, values []int) {
+	first, last := values[0], values[len(values)-1]
+	fmt.Fprintf(w, "%s\t%s\t%d -> %d (%+d)\n", name, Sparkline(values), first, last, last-first)
+}
+
+// WriteCSV writes a row for each point with the total and the counts of each
+// type and author in "type:NAME" and "author:NAME" columns.
+func WriteCSV(w io.Writer, points []Point) error {
+	typeNames, authorNames := types(points), authors(points)
+
+	header := []string{"time", "commit", "total"}
+	for _, t := range typeNames {
+		header = append(header, "type:"+t)
+	}
+	for _, a := range authorNames {
+		header = append(header, "author:"+a)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		row := []string{p.Time.Format(time.RFC3339), p.Commit, strconv.Itoa(p.Total)}
+		for _, t := range typeNames {
+			row = append(row, strconv.Itoa(p.ByType[t]))
+		}
+		for _, a := range authorNames {
+			row = append(row, strconv.Itoa(p.ByAuthor[a]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the points as a JSON array.
+func WriteJSON(w io.Writer, points []Point) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(points)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/euforic/todos/filter"
+	"github.com/euforic/todos/history"
+	"github.com/euforic/todos/pkg/duration"
+)
+
+var historyCommand = &command{
+	name:    "history",
+	usage:   "history [options] [dir]",
+	summary: "Count comments across the git history to show how they trend",
+	run:     runHistory,
+}
+
+var errHistoryPaths = errors.New("history takes a single directory and does not support -files-from")
+
+// runHistory runs the history command
+func runHistory(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	since := fs.String("since", "6mo", "How far back to go, e.g. 90d, 6mo or 1y")
+	step := fs.String("step", "1w", "Time between samples, e.g. 1d, 1w or 1mo")
+	rev := fs.String("rev", "HEAD", "Revision whose history is walked")
+	output := fs.String("output", "table", "Output style (table, csv, json)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 || flags.filesFrom != "" {
+		return errHistoryPaths
+	}
+	dir := searchDir(fs)
+
+	sinceDuration, err := duration.Parse(*since)
+	if err != nil {
+		return err
+	}
+	stepDuration, err := duration.Parse(*step)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(fs, flags, dir)
+	if err != nil {
+		return err
+	}
+
+	ignoreList, err := buildIgnoreList(cfg, []string{dir})
+	if err != nil {
+		return err
+	}
+
+	f, err := filter.Parse(cfg.Filter)
+	if err != nil {
+		return err
+	}
+
+	aliases, err := loadAuthors(cfg, dir)
+	if err != nil {
+		return err
+	}
+
+	var author func(string) string
+	if aliases != nil {
+		author = aliases.Canonical
+	}
+
+	m, err := newMatcher(cfg)
+	if err != nil {
+		return err
+	}
+
+	points, err := history.Collect(history.Options{
+		Dir:     dir,
+		Rev:     *rev,
+		Since:   sinceDuration,
+		Step:    stepDuration,
+		Matcher: m,
+		Ignores: ignoreList,
+		Author:  author,
+		Match:   f.Match,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch *output {
+	case "csv":
+		return history.WriteCSV(os.Stdout, points)
+	case "json":
+		return history.WriteJSON(os.Stdout, points)
+	case "table":
+		return history.WriteTable(os.Stdout, points)
+	default:
+		return fmt.Errorf("unknown output style %q (table, csv, json)", *output)
+	}
+}
@@ -0,0 +1,529 @@
+// Package lsp implements a Language Server Protocol server that reports
+// comments found by todos as diagnostics.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/euforic/todos/todos"
+)
+
+const source = "todos"
+
+// issuePlaceholder is inserted by the "add issue reference" code action for
+// the user to replace with the issue. It is not a reference itself, so the
+// action is still offered until it is replaced.
+const issuePlaceholder = "#ISSUE"
+
+var (
+	// ErrExitWithoutShutdown is returned by Serve when the client sends exit
+	// before shutdown. The process should exit with status 1.
+	ErrExitWithoutShutdown = errors.New("exit notification received before shutdown")
+)
+
+// Options configures a Server.
+type Options struct {
+	// Types are the comment types to search for.
+	Types []string
+	// Permissive enables the looser comment format.
+	Permissive bool
+	// Matcher finds the comments in documents instead of Types and
+	// Permissive if set.
+	Matcher *todos.Matcher
+	// Severities maps comment types to their severity, which sets the
+	// severity of their diagnostics. Other types use todos.DefaultSeverities.
+	Severities map[string]string
+	// User is the author inserted by the "assign to me" code action. The
+	// action is not offered if User is empty.
+	User string
+	// Search finds the comments in the workspace roots for workspace
+	// symbols. Defaults to todos.SearchPaths, skipping hidden files.
+	Search func(roots []string) ([]todos.Comment, error)
+	// Configure returns the options for the workspace roots sent by the
+	// client in initialize, such as those of the workspace's config file.
+	// The returned options replace these, except for Configure itself.
+	Configure func(roots []string) (Options, error)
+}
+
+// Server is a language server that publishes comments as diagnostics.
+type Server struct {
+	opts Options
+	conn *conn
+
+	mu          sync.Mutex
+	docs        map[string]string
+	roots       []string
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a server using opts.
+func NewServer(opts Options) *Server {
+	return &Server{
+		opts: withDefaults(opts),
+		docs: map[string]string{},
+	}
+}
+
+// withDefaults returns opts with the default matcher and search if unset.
+func withDefaults(opts Options) Options {
+	if opts.Matcher == nil {
+		// The matcher can't be invalid without patterns.
+		opts.Matcher, _ = todos.NewMatcher(todos.MatchOptions{Types: opts.Types, Permissive: opts.Permissive})
+	}
+	if opts.Search == nil {
+		opts.Search = func(roots []string) ([]todos.Comment, error) {
+			return opts.Matcher.SearchPaths(roots, []string{".*"})
+		}
+	}
+
+	return opts
+}
+
+// Serve handles messages read from r and writes responses to w until the
+// client sends exit or r is closed.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+
+	for {
+		body, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: codeInvalidRequest, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if s.isShutdown() {
+				return nil
+			}
+			return ErrExitWithoutShutdown
+		}
+
+		result, rerr := s.handle(req)
+		if req.ID == nil {
+			// Notifications have no response.
+			continue
+		}
+
+		if err := s.reply(req.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) isShutdown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdown
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	return s.conn.write(response{JSONRPC: jsonrpcVersion, ID: id, Result: result, Error: rerr})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.conn.write(notification{JSONRPC: jsonrpcVersion, Method: method, Params: params})
+}
+
+// handle dispatches a request or notification to its handler.
+func (s *Server) handle(req request) (interface{}, *responseError) {
+	if req.Method == "initialize" {
+		var params initializeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.initialize(params)
+	}
+
+	s.mu.Lock()
+	initialized := s.initialized
+	s.mu.Unlock()
+	if !initialized {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	}
+
+	switch req.Method {
+	case "initialized", "textDocument/didSave", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.setDocument(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		// The server only supports full document sync, so the last change
+		// holds the whole document.
+		if n := len(params.ContentChanges); n > 0 {
+			s.setDocument(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.closeDocument(params.TextDocument.URI)
+		return nil, nil
+	case "workspace/symbol":
+		var params workspaceSymbolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		symbols, err := s.workspaceSymbols(params.Query)
+		if err != nil {
+			return nil, &responseError{Code: codeInvalidRequest, Message: err.Error()}
+		}
+		return symbols, nil
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.codeActions(params), nil
+	}
+
+	if req.ID == nil {
+		// Unknown notifications are ignored.
+		return nil, nil
+	}
+
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func (s *Server) initialize(params initializeParams) (interface{}, *responseError) {
+	roots := []string{}
+	for _, folder := range params.WorkspaceFolders {
+		if path := uriToPath(folder.URI); path != "" {
+			roots = append(roots, path)
+		}
+	}
+	if len(roots) == 0 {
+		if path := uriToPath(params.RootURI); path != "" {
+			roots = append(roots, path)
+		} else if params.RootPath != "" {
+			roots = append(roots, params.RootPath)
+		}
+	}
+
+	if s.opts.Configure != nil {
+		opts, err := s.opts.Configure(roots)
+		if err != nil {
+			return nil, &responseError{Code: codeInvalidRequest, Message: err.Error()}
+		}
+		opts.Configure = s.opts.Configure
+		s.opts = withDefaults(opts)
+	}
+
+	s.mu.Lock()
+	s.roots = roots
+	s.initialized = true
+	s.mu.Unlock()
+
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync:        syncFull,
+			WorkspaceSymbolProvider: true,
+			CodeActionProvider:      true,
+		},
+		ServerInfo: serverInfo{Name: source},
+	}, nil
+}
+
+// setDocument stores the text of an open document and publishes its diagnostics.
+func (s *Server) setDocument(uri, text string) {
+	s.mu.Lock()
+	s.docs[uri] = text
+	s.mu.Unlock()
+
+	_ = s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: s.diagnostics(uri, text),
+	})
+}
+
+// closeDocument forgets a document and clears its diagnostics.
+func (s *Server) closeDocument(uri string) {
+	s.mu.Lock()
+	delete(s.docs, uri)
+	s.mu.Unlock()
+
+	_ = s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: []diagnostic{},
+	})
+}
+
+// located is a comment with the position of its type in the document.
+type located struct {
+	todos.Comment
+	typeRange textRange
+	textEnd   position
+}
+
+// parseDocument parses the in-memory text of the document at uri.
+func (s *Server) parseDocument(uri, text string) []located {
+	path := uriToPath(uri)
+	if path == "" {
+		path = uri
+	}
+
+	matches, err := s.opts.Matcher.Matches(strings.NewReader(text), path)
+	if err != nil {
+		return nil
+	}
+
+	return locate(matches, strings.Split(text, "\n"))
+}
+
+// locate converts the byte offsets of each match in lines to positions.
+func locate(matches []todos.Match, lines []string) []located {
+	result := make([]located, 0, len(matches))
+	for _, match := range matches {
+		comment := match.Comment
+		if comment.Line < 1 || comment.Line > len(lines) {
+			continue
+		}
+
+		line := strings.TrimSuffix(lines[comment.Line-1], "\r")
+		start, end := match.Start, match.End
+		if end > len(line) {
+			end = len(line)
+		}
+		if start > end {
+			start = end
+		}
+
+		textEnd := len(line)
+		if comment.Text != "" {
+			if i := strings.LastIndex(line, comment.Text); i >= end {
+				textEnd = i + len(comment.Text)
+			}
+		}
+
+		row := comment.Line - 1
+		result = append(result, located{
+			Comment: comment,
+			typeRange: textRange{
+				Start: position{Line: row, Character: utf16Len(line[:start])},
+				End:   position{Line: row, Character: utf16Len(line[:end])},
+			},
+			textEnd: position{Line: row, Character: utf16Len(line[:textEnd])},
+		})
+	}
+	return result
+}
+
+func (s *Server) diagnostics(uri, text string) []diagnostic {
+	diagnostics := []diagnostic{}
+	for _, c := range s.parseDocument(uri, text) {
+		comments := []todos.Comment{c.Comment}
+		todos.AssignSeverities(comments, s.opts.Severities)
+
+		severity := severityInformation
+		switch comments[0].Severity {
+		case todos.SeverityWarning:
+			severity = severityWarning
+		case todos.SeverityError:
+			severity = severityError
+		}
+
+		r := c.typeRange
+		r.End = c.textEnd
+
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    r,
+			Severity: severity,
+			Code:     c.Type,
+			Source:   source,
+			Message:  todos.Summary(c.Comment),
+		})
+	}
+	return diagnostics
+}
+
+// workspaceSymbols returns a symbol for every comment in the workspace whose
+// title contains query. Open documents are used in place of the files on disk.
+func (s *Server) workspaceSymbols(query string) ([]symbolInformation, error) {
+	s.mu.Lock()
+	roots := append([]string{}, s.roots...)
+	docs := make(map[string]string, len(s.docs))
+	for uri, text := range s.docs {
+		docs[uri] = text
+	}
+	s.mu.Unlock()
+
+	found := []located{}
+	uris := []string{}
+
+	open := map[string]bool{}
+	for uri, text := range docs {
+		open[uriToPath(uri)] = true
+		for _, c := range s.parseDocument(uri, text) {
+			found = append(found, c)
+			uris = append(uris, uri)
+		}
+	}
+
+	if len(roots) > 0 {
+		comments, err := s.opts.Search(roots)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range comments {
+			path, err := filepath.Abs(c.File)
+			if err != nil || open[path] {
+				continue
+			}
+			// Files on disk are not read again, so the position is the start of the line.
+			found = append(found, located{
+				Comment:   c,
+				typeRange: textRange{Start: position{Line: c.Line - 1}, End: position{Line: c.Line - 1}},
+			})
+			uris = append(uris, pathToURI(path))
+		}
+	}
+
+	query = strings.ToLower(query)
+	symbols := []symbolInformation{}
+	for i, c := range found {
+		name := todos.Summary(c.Comment)
+		if query != "" && !strings.Contains(strings.ToLower(name), query) {
+			continue
+		}
+
+		symbols = append(symbols, symbolInformation{
+			Name:          name,
+			Kind:          symbolKindString,
+			Location:      location{URI: uris[i], Range: c.typeRange},
+			ContainerName: filepath.Base(c.File),
+		})
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i].Location, symbols[j].Location
+		if a.URI == b.URI {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.URI < b.URI
+	})
+
+	return symbols, nil
+}
+
+// codeActions returns the actions for the comments on the lines in the range.
+func (s *Server) codeActions(params codeActionParams) []codeAction {
+	uri := params.TextDocument.URI
+
+	s.mu.Lock()
+	text, ok := s.docs[uri]
+	s.mu.Unlock()
+	if !ok {
+		return []codeAction{}
+	}
+
+	actions := []codeAction{}
+	for _, c := range s.parseDocument(uri, text) {
+		line := c.Line - 1
+		if line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+
+		if c.Author == "" && s.opts.User != "" {
+			at := c.typeRange.End
+			actions = append(actions, codeAction{
+				Title: fmt.Sprintf("Assign %s to %s", c.Type, s.opts.User),
+				Kind:  codeActionQuickFix,
+				Edit:  insert(uri, at, "("+s.opts.User+")"),
+			})
+		}
+
+		if c.Issue == "" {
+			actions = append(actions, codeAction{
+				Title: fmt.Sprintf("Add issue reference to %s", c.Type),
+				Kind:  codeActionQuickFix,
+				Edit:  insert(uri, c.textEnd, " ("+issuePlaceholder+")"),
+			})
+		}
+	}
+
+	return actions
+}
+
+func insert(uri string, at position, text string) workspaceEdit {
+	return workspaceEdit{Changes: map[string][]textEdit{
+		uri: {{Range: textRange{Start: at, End: at}, NewText: text}},
+	}}
+}
+
+// utf16Len returns the length of s in UTF-16 code units, which LSP positions
+// are measured in.
+func utf16Len(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		n += len(utf16.Encode([]rune{r}))
+		s = s[size:]
+	}
+	return n
+}
+
+// uriToPath returns the file system path of a file URI, or an empty string.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+
+	path := u.Path
+	// Windows paths are written as file:///C:/path.
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path)
+}
+
+// pathToURI returns the file URI of an absolute path.
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
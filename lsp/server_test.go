@@ -0,0 +1,245 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+// client drives a Server over in-memory pipes.
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+	done chan error
+}
+
+func newClient(t *testing.T, opts Options) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	c := &client{t: t, conn: newConn(clientIn, clientOut), done: make(chan error, 1)}
+
+	go func() {
+		c.done <- NewServer(opts).Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	return c
+}
+
+func (c *client) send(method string, params interface{}, withID bool) {
+	c.t.Helper()
+
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if withID {
+		c.id++
+		msg["id"] = c.id
+	}
+
+	if err := c.conn.write(msg); err != nil {
+		c.t.Fatalf("write %s: %v", method, err)
+	}
+}
+
+// next reads the next message from the server into v.
+func (c *client) next(v interface{}) {
+	c.t.Helper()
+
+	body, err := c.conn.read()
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		c.t.Fatalf("unmarshal %s: %v", body, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	c := newClient(t, Options{
+		Types: []string{"TODO", "FIXME"},
+		User:  "alice",
+		Search: func(roots []string) ([]todos.Comment, error) {
+			return []todos.Comment{
+				{File: "/work/other.go", Line: 3, Type: "TODO", Text: "on disk"},
+				{File: "/work/main.go", Line: 9, Type: "TODO", Text: "stale copy"},
+			}, nil
+		},
+	})
+
+	c.send("initialize", map[string]interface{}{"rootUri": "file:///work"}, true)
+	var initResp struct {
+		Result initializeResult `json:"result"`
+	}
+	c.next(&initResp)
+	if !initResp.Result.Capabilities.CodeActionProvider || initResp.Result.Capabilities.TextDocumentSync != syncFull {
+		t.Fatalf("initialize capabilities = %+v", initResp.Result.Capabilities)
+	}
+	c.send("initialized", map[string]interface{}{}, false)
+
+	uri := "file:///work/main.go"
+	c.send("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "version": 1, "text": "package main\n\n// TODO: unsaved ✓ work\n"},
+	}, false)
+
+	var published struct {
+		Method string                   `json:"method"`
+		Params publishDiagnosticsParams `json:"params"`
+	}
+	c.next(&published)
+
+	wantDiagnostics := publishDiagnosticsParams{
+		URI: uri,
+		Diagnostics: []diagnostic{{
+			Range:    textRange{Start: position{Line: 2, Character: 3}, End: position{Line: 2, Character: 23}},
+			Severity: severityInformation,
+			Code:     "TODO",
+			Source:   source,
+			Message:  "TODO: unsaved ✓ work",
+		}},
+	}
+	if published.Method != "textDocument/publishDiagnostics" || !cmp.Equal(published.Params, wantDiagnostics) {
+		t.Fatalf("didOpen published %s\n%s", published.Method, cmp.Diff(published.Params, wantDiagnostics))
+	}
+
+	c.send("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]interface{}{{"text": "// FIXME(bob): changed #12\n"}},
+	}, false)
+	c.next(&published)
+	if len(published.Params.Diagnostics) != 1 || published.Params.Diagnostics[0].Severity != severityWarning {
+		t.Fatalf("didChange published %+v", published.Params)
+	}
+
+	c.send("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+		"contentChanges": []map[string]interface{}{{"text": "// TODO: fix\n"}},
+	}, false)
+	c.next(&published)
+
+	c.send("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range":        textRange{Start: position{Line: 0}, End: position{Line: 0}},
+	}, true)
+	var actions struct {
+		Result []codeAction `json:"result"`
+	}
+	c.next(&actions)
+
+	wantActions := []codeAction{
+		{Title: "Assign TODO to alice", Kind: codeActionQuickFix, Edit: insert(uri, position{Line: 0, Character: 7}, "(alice)")},
+		{Title: "Add issue reference to TODO", Kind: codeActionQuickFix, Edit: insert(uri, position{Line: 0, Character: 12}, " ("+issuePlaceholder+")")},
+	}
+	if !cmp.Equal(actions.Result, wantActions) {
+		t.Fatalf("codeAction \n%s", cmp.Diff(actions.Result, wantActions))
+	}
+
+	c.send("workspace/symbol", map[string]interface{}{"query": ""}, true)
+	var symbols struct {
+		Result []symbolInformation `json:"result"`
+	}
+	c.next(&symbols)
+
+	// The open buffer replaces the stale copy of main.go on disk.
+	wantSymbols := []symbolInformation{
+		{Name: "TODO: fix", Kind: symbolKindString, ContainerName: "main.go", Location: location{URI: uri, Range: textRange{Start: position{Line: 0, Character: 3}, End: position{Line: 0, Character: 7}}}},
+		{Name: "TODO: on disk", Kind: symbolKindString, ContainerName: "other.go", Location: location{URI: "file:///work/other.go", Range: textRange{Start: position{Line: 2}, End: position{Line: 2}}}},
+	}
+	if !cmp.Equal(symbols.Result, wantSymbols) {
+		t.Fatalf("workspace/symbol \n%s", cmp.Diff(symbols.Result, wantSymbols))
+	}
+
+	c.send("workspace/symbol", map[string]interface{}{"query": "DISK"}, true)
+	c.next(&symbols)
+	if len(symbols.Result) != 1 {
+		t.Fatalf("workspace/symbol query = %+v", symbols.Result)
+	}
+
+	c.send("unknown/method", map[string]interface{}{}, true)
+	var unknown struct {
+		Result interface{}    `json:"result"`
+		Error  *responseError `json:"error"`
+	}
+	c.next(&unknown)
+	if unknown.Error == nil || unknown.Error.Code != codeMethodNotFound {
+		t.Fatalf("unknown method response = %+v", unknown)
+	}
+
+	c.send("shutdown", nil, true)
+	c.next(&unknown)
+	c.send("exit", nil, false)
+
+	if err := <-c.done; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := newClient(t, Options{Types: []string{"TODO"}})
+	c.send("exit", nil, false)
+
+	if err := <-c.done; err != ErrExitWithoutShutdown {
+		t.Fatalf("Serve() error = %v, want %v", err, ErrExitWithoutShutdown)
+	}
+}
+
+func TestParseDocumentTypeRange(t *testing.T) {
+	m, err := todos.NewMatcher(todos.MatchOptions{
+		Types:       []string{"FIXME"},
+		TypeAliases: map[string][]string{"FIXME": {"FIX-ME"}},
+		Patterns:    []todos.Pattern{{Regex: `@todo\((?P<author>\w+)\)\s*(?P<text>.*)`, Type: "TODO"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(Options{Matcher: m})
+
+	text := "x := 1 // FIX-ME: alias\n  // @todo(bob) pattern\n"
+	got := []textRange{}
+	for _, c := range s.parseDocument("file:///work/main.go", text) {
+		got = append(got, c.typeRange)
+	}
+
+	want := []textRange{
+		{Start: position{Line: 0, Character: 10}, End: position{Line: 0, Character: 16}},
+		{Start: position{Line: 1, Character: 5}, End: position{Line: 1, Character: 10}},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("parseDocument() type ranges \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestServerConfigure(t *testing.T) {
+	var gotRoots []string
+	c := newClient(t, Options{
+		Configure: func(roots []string) (Options, error) {
+			gotRoots = roots
+			return Options{Types: []string{"NOTE"}}, nil
+		},
+	})
+
+	c.send("initialize", map[string]interface{}{"rootUri": "file:///work"}, true)
+	var initResp struct {
+		Result initializeResult `json:"result"`
+	}
+	c.next(&initResp)
+	if want := []string{"/work"}; !cmp.Equal(gotRoots, want) {
+		t.Errorf("Configure() roots = %q, want %q", gotRoots, want)
+	}
+
+	c.send("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": "file:///work/main.go", "version": 1, "text": "// TODO: skipped\n// NOTE: found\n"},
+	}, false)
+
+	var published struct {
+		Params publishDiagnosticsParams `json:"params"`
+	}
+	c.next(&published)
+	if len(published.Params.Diagnostics) != 1 || published.Params.Diagnostics[0].Code != "NOTE" {
+		t.Errorf("didOpen published %+v, want the NOTE of the configured types", published.Params)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/euforic/todos/lsp"
+	"github.com/euforic/todos/todos"
+)
+
+var lspCommand = &command{
+	name:    "lsp",
+	usage:   "lsp [options]",
+	summary: "Run a language server over stdio that reports comments as diagnostics",
+	run:     runLSP,
+}
+
+// runLSP runs the lsp command
+func runLSP(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	user := fs.String("user", os.Getenv("USER"), "Author inserted by the 'assign to me' code action")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// The config and matcher are set up for the workspace the editor opens,
+	// which is not necessarily the working directory.
+	server := lsp.NewServer(lsp.Options{
+		Configure: func(roots []string) (lsp.Options, error) {
+			return workspaceOptions(fs, flags, roots, *user)
+		},
+	})
+
+	err := server.Serve(os.Stdin, os.Stdout)
+	if errors.Is(err, lsp.ErrExitWithoutShutdown) {
+		return &exitError{code: 1}
+	}
+
+	return err
+}
+
+// workspaceOptions returns the language server options for the config file
+// found from the first workspace root, or from the working directory without
+// roots. Workspace symbols are searched through a cache, kept in memory
+// unless the config sets a cache file, so only changed files are parsed again.
+func workspaceOptions(fs *flag.FlagSet, flags *searchFlags, roots []string, user string) (lsp.Options, error) {
+	dir := "."
+	if len(roots) > 0 {
+		dir = roots[0]
+	}
+
+	cfg, err := loadConfig(fs, flags, dir)
+	if err != nil {
+		return lsp.Options{}, err
+	}
+
+	m, err := newMatcher(cfg)
+	if err != nil {
+		return lsp.Options{}, err
+	}
+
+	cache := m.NewCache()
+	if cfg.Cache != "" {
+		if cache, err = m.OpenCache(cfg.Cache); err != nil {
+			return lsp.Options{}, err
+		}
+	}
+
+	return lsp.Options{
+		Matcher:    m,
+		Severities: cfg.Severity,
+		User:       user,
+		Search: func(roots []string) ([]todos.Comment, error) {
+			comments, err := scanCache(cfg, cache, roots)
+			if err != nil {
+				return nil, err
+			}
+			return comments, annotateComments(cfg, configDir(roots), comments)
+		},
+	}, nil
+}
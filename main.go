@@ -1,124 +1,161 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
-
-	"github.com/euforic/todos/todos"
+	"text/tabwriter"
 )
 
-func main() {
-	// Define command line flags
-	ignores := flag.String("ignore", "", "Comma-separated list of files and directories to ignore")
-	sortBy := flag.String("sortby", "", "Sort results by field (author, file, line, type, text) to sort descending, postfix with ':desc' (e.g. author:desc)")
-	commentTypesStr := flag.String("types", "TODO,FIXME", "Comma-separated list of comment types to search for")
-	searchHidden := flag.Bool("hidden", false, "Search hidden files and directories")
-	permissive := flag.Bool("permissive", false, "Permissive mode (looser regex, but can match more than intended, strict format is 'TYPE(author): text' where author is optional)")
-	validateMax := flag.Int("validate-max", 0, "Validate that the number of comments is less than or equal to the max")
-	outputStyle := flag.String("output", "table", "Output style (table, group, json, md)")
-	format := flag.String("format", "", "Go template string to use for output style (-output will be ignored if format is set)")
-	noGitingore := flag.Bool("no-gitignore", false, "Ignore .gitignore file")
-	flag.Parse()
-
-	dir := flag.Arg(0)
-	if dir == "" {
-		dir = "."
+// command is a todos subcommand
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cmd *command, args []string) error
+}
+
+// commands are the available subcommands, in the order they are listed in the help
+var commands []*command
+
+func init() {
+	commands = []*command{
+		scanCommand,
+		checkCommand,
+		reportCommand,
+		statsCommand,
+		historyCommand,
+		diffCommand,
+		syncCommand,
+		tuiCommand,
+		serveCommand,
+		lspCommand,
+		configCommand,
 	}
+}
 
-	ignoreList := parseIgnoreList(ignores, searchHidden, dir)
+// exitError is returned by a command to exit with a specific code without
+// printing an error
+type exitError struct {
+	code int
+}
 
-	if !*noGitingore {
-		ignorePatterns, err := todos.ParseGitignore(dir)
-		if err != nil && !os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
-			os.Exit(1)
-		}
-		ignoreList = append(ignoreList, ignorePatterns...)
-	}
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
 
-	commentTypes := strings.Split(*commentTypesStr, ",")
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	comments, err := todos.Search(dir, commentTypes, ignoreList, *permissive)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
-		os.Exit(1)
+// run runs the command given by args and returns the exit code
+func run(args []string) int {
+	cmd, args := findCommand(args)
+	if cmd == nil {
+		printUsage(os.Stderr)
+		if len(args) > 0 && args[0] == "help" {
+			return 0
+		}
+		return 2
 	}
 
-	validateCommentsCount(*validateMax, comments)
+	err := cmd.run(cmd, args)
 
-	sortField, sortDesc := parseSortBy(*sortBy)
-
-	formatStr := ""
-	if *format != "" {
-		*outputStyle = "format"
-		formatStr = *format
+	var exitErr *exitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return 1
 	}
-
-	outputComments(*outputStyle, comments, sortField, sortDesc, formatStr)
 }
 
-// parseIgnoreList parses the ignore list from the command line
-func parseIgnoreList(ignores *string, searchHidden *bool, dir string) []string {
-	ignoreList := []string{}
+// findCommand returns the command named by the first argument and the
+// remaining arguments. For backward compatibility, arguments that don't start
+// with a command name run the scan command. A nil command is returned when
+// the command list should be printed.
+func findCommand(args []string) (*command, []string) {
+	if len(args) == 0 {
+		return scanCommand, args
+	}
 
-	if *ignores != "" {
-		ignoreList = strings.Split(*ignores, ",")
+	if args[0] == "help" {
+		if len(args) > 1 {
+			if cmd := lookupCommand(args[1]); cmd != nil {
+				return cmd, []string{"-h"}
+			}
+		}
+		return nil, args
 	}
 
-	if !*searchHidden {
-		ignoreList = append(ignoreList, ".*")
+	if cmd := lookupCommand(args[0]); cmd != nil {
+		return cmd, args[1:]
 	}
 
-	return ignoreList
+	return scanCommand, args
 }
 
-// validateCommentsCount validates that the number of comments is less than or equal to the max
-func validateCommentsCount(validateMax int, comments []todos.Comment) {
-	if validateMax > 0 && len(comments) > validateMax {
-		fmt.Fprintf(os.Stderr, "Error: %d comments found, max is %d", len(comments), validateMax)
-		os.Exit(1)
+// lookupCommand returns the command with the given name or nil
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
 	}
+	return nil
 }
 
-// parseSortBy parses the sortby flag from the command line
-func parseSortBy(sortBy string) (string, bool) {
-	sortField := ""
-	sortDesc := false
+// printUsage prints the list of commands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n  todos <command> [options] [args]\n  todos [scan options] [dir]\n\nCommands:\n")
 
-	sortbyParts := strings.Split(sortBy, ":")
-	if len(sortbyParts) > 0 {
-		sortField = sortbyParts[0]
+	tabW := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tabW, "  %s\t%s\n", cmd.name, cmd.summary)
 	}
+	_ = tabW.Flush()
 
-	if len(sortbyParts) > 1 && sortbyParts[1] == "desc" {
-		sortDesc = true
-	}
+	fmt.Fprintf(w, "\nRun 'todos help <command>' for the options of a command.\n")
+}
 
-	return sortField, sortDesc
+// newFlagSet returns a flag set for the command that prints the command
+// usage and its options on -h
+func newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: todos %s\n\n%s.\n\nOptions:\n", cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+		if cmd.name == "scan" {
+			fmt.Fprintf(out, "\n")
+			printUsage(out)
+		}
+	}
+	return fs
 }
 
-// outputComments outputs the comments in the specified format
-func outputComments(outputStyle string, comments []todos.Comment, sortField string, sortDesc bool, formatStr string) {
-	var outputErr error
-	switch outputStyle {
-	case "table":
-		outputErr = todos.WriteTable(os.Stdout, comments, sortField, sortDesc)
-	case "group":
-		outputErr = todos.WriteFileGroup(os.Stdout, comments, sortField, sortDesc)
-	case "json":
-		outputErr = todos.WriteJSON(os.Stdout, comments, sortField, sortDesc)
-	case "md":
-		outputErr = todos.WriteMarkdown(os.Stdout, comments, sortField, sortDesc)
-	case "format":
-		outputErr = todos.WriteTemplate(os.Stdout, comments, sortField, sortDesc, formatStr)
-	default:
-		outputErr = todos.WriteTable(os.Stdout, comments, sortField, sortDesc)
+// parseFlags parses args with fs. Invalid flags have already been reported by
+// the flag package, so they are returned as a usage exit code.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return &exitError{code: 2}
 	}
+	return err
+}
 
-	if outputErr != nil {
-		fmt.Fprintf(os.Stderr, "Error: %s", outputErr.Error())
-		os.Exit(1)
+// splitList splits a comma-separated flag value
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
 	}
+	return strings.Split(s, ",")
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/pkg/codeowners"
+	"github.com/euforic/todos/todos"
+)
+
+// codeOwners is a CODEOWNERS file and the directory its patterns are relative to
+type codeOwners struct {
+	file *codeowners.File
+	root string
+}
+
+// loadCodeOwners loads the configured CODEOWNERS file, or else the first one
+// found from dir upward. nil is returned if there is none.
+func loadCodeOwners(cfg *config.Config, dir string) (*codeOwners, error) {
+	path := cfg.CodeOwners
+	if path == "" {
+		found, err := codeowners.Find(dir)
+		if err != nil {
+			return nil, err
+		}
+		path = found
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := codeowners.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.Abs(codeowners.Root(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return &codeOwners{file: file, root: root}, nil
+}
+
+// assign sets the owners of the comments in files below the root
+func (o *codeOwners) assign(comments []todos.Comment) {
+	if o == nil {
+		return
+	}
+
+	for i := range comments {
+		abs, err := filepath.Abs(comments[i].File)
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(o.root, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if owners := o.file.Owners(rel); len(owners) > 0 {
+			comments[i].Owners = owners
+		}
+	}
+}
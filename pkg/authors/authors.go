@@ -0,0 +1,248 @@
+// Package authors maps the variants of an author's name, such as handles,
+// nicknames and email addresses, to one canonical name.
+package authors
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the name of the todos alias file.
+const FileName = ".todos-authors"
+
+// MailmapName is the name of git's mailmap file.
+const MailmapName = ".mailmap"
+
+var errSyntax = errors.New("syntax error")
+
+// Map maps author names to canonical names. Names are matched case
+// insensitively. The zero value is not usable, use New.
+type Map struct {
+	names map[string]string
+}
+
+// New returns an empty Map.
+func New() *Map {
+	return &Map{names: map[string]string{}}
+}
+
+// Add maps aliases to canonical. The canonical name maps to itself unless it
+// is already an alias of another name, so names that only differ in case are
+// normalized too. Later aliases override earlier ones.
+func (m *Map) Add(canonical string, aliases ...string) {
+	canonical = strings.TrimSpace(canonical)
+	if canonical == "" {
+		return
+	}
+
+	if _, ok := m.names[key(canonical)]; !ok {
+		m.names[key(canonical)] = canonical
+	}
+
+	for _, alias := range aliases {
+		if alias = strings.TrimSpace(alias); alias != "" && key(alias) != key(canonical) {
+			m.names[key(alias)] = canonical
+		}
+	}
+}
+
+// Merge adds the names of other to m, overriding those already in m.
+func (m *Map) Merge(other *Map) {
+	for k, v := range other.names {
+		m.names[k] = v
+	}
+}
+
+// Len returns the number of names in m.
+func (m *Map) Len() int {
+	return len(m.names)
+}
+
+// Canonical returns the canonical name for author, or author itself if it
+// has none. An author such as "Jane Doe <jane@example.com>" is also looked
+// up by its email address. Aliases are followed, so an alias may map to a
+// name that is itself an alias.
+func (m *Map) Canonical(author string) string {
+	name, ok := m.names[key(author)]
+	if !ok {
+		email := emailOf(author)
+		if email == "" {
+			return author
+		}
+		if name, ok = m.names[key(email)]; !ok {
+			return author
+		}
+	}
+
+	seen := map[string]bool{key(author): true}
+	for !seen[key(name)] {
+		seen[key(name)] = true
+		next, ok := m.names[key(name)]
+		if !ok {
+			break
+		}
+		name = next
+	}
+
+	return name
+}
+
+// key returns the lookup key of a name
+func key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// emailOf returns the address in angle brackets in s, or an empty string
+func emailOf(s string) string {
+	start := strings.Index(s, "<")
+	end := strings.LastIndex(s, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return strings.TrimSpace(s[start+1 : end])
+}
+
+// Parse parses an alias file. Each line has a canonical name, a colon and a
+// comma-separated list of its aliases:
+//
+//	jdoe: john.doe, John, john.doe@example.com
+//
+// Blank lines and lines starting with # are ignored.
+func Parse(r io.Reader) (*Map, error) {
+	m := New()
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		canonical, aliases, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(canonical) == "" {
+			return nil, fmt.Errorf("%w: line %d: expected \"name: alias, ...\"", errSyntax, n)
+		}
+
+		m.Add(canonical, strings.Split(aliases, ",")...)
+	}
+
+	return m, scanner.Err()
+}
+
+// ParseMailmap parses a git mailmap file. The proper name of an entry is its
+// canonical name, or its proper email if it has no name, and the commit name
+// and emails are aliases of it:
+//
+//	Proper Name <proper@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+func ParseMailmap(r io.Reader) (*Map, error) {
+	m := New()
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		names, emails, err := splitMailmap(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", errSyntax, n, err)
+		}
+
+		canonical := names[0]
+		if canonical == "" {
+			canonical = emails[0]
+		}
+
+		aliases := []string{emails[0]}
+		if len(emails) > 1 {
+			aliases = append(aliases, names[1], emails[1])
+		}
+		m.Add(canonical, aliases...)
+	}
+
+	return m, scanner.Err()
+}
+
+// splitMailmap splits a mailmap line into its one or two names, which may be
+// empty, and their emails
+func splitMailmap(line string) ([]string, []string, error) {
+	names := []string{}
+	emails := []string{}
+
+	for strings.TrimSpace(line) != "" {
+		start := strings.Index(line, "<")
+		end := strings.Index(line, ">")
+		if start < 0 || end < start {
+			return nil, nil, errors.New("expected <email>")
+		}
+
+		names = append(names, strings.TrimSpace(line[:start]))
+		emails = append(emails, strings.TrimSpace(line[start+1:end]))
+		line = line[end+1:]
+	}
+
+	if len(emails) == 0 || len(emails) > 2 {
+		return nil, nil, errors.New("expected one or two <email>")
+	}
+
+	return names, emails, nil
+}
+
+// Find walks up from dir looking for a file with the given name and returns
+// its path. An empty path is returned if none is found.
+func Find(dir, name string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load parses the alias file at path, or the mailmap file if its name is
+// .mailmap.
+func Load(path string) (*Map, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parse := Parse
+	if filepath.Base(path) == MailmapName {
+		parse = ParseMailmap
+	}
+
+	m, err := parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,132 @@
+package authors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse(strings.NewReader(`
+# canonical: aliases
+jdoe: john.doe, John, john.doe@example.com
+Alice Smith: alice, asmith
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		author string
+		want   string
+	}{
+		{author: "john.doe", want: "jdoe"},
+		{author: "JOHN", want: "jdoe"},
+		{author: "John.Doe@Example.com", want: "jdoe"},
+		{author: "JDoe", want: "jdoe"},
+		{author: "asmith", want: "Alice Smith"},
+		{author: "alice smith", want: "Alice Smith"},
+		{author: "John <john.doe@example.com>", want: "jdoe"},
+		{author: "bob", want: "bob"},
+		{author: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := m.Canonical(tt.author); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.author, got, tt.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Parse(strings.NewReader("jdoe john\n")); err == nil {
+		t.Error("Parse() error = nil for a line without a colon")
+	}
+}
+
+func TestParseMailmap(t *testing.T) {
+	m, err := ParseMailmap(strings.NewReader(`
+# git mailmap
+Jane Doe <jane@example.com>
+<jane@example.com> <jane@old.example.com>
+Joe Bloggs <joe@example.com> <joe@laptop.local>
+Joe Bloggs <joe@example.com> jb <jb@example.org>  # comment
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		author string
+		want   string
+	}{
+		{author: "jane@example.com", want: "Jane Doe"},
+		{author: "jane@old.example.com", want: "Jane Doe"},
+		{author: "joe@laptop.local", want: "Joe Bloggs"},
+		{author: "jb", want: "Joe Bloggs"},
+		{author: "jb@example.org", want: "Joe Bloggs"},
+		{author: "joe bloggs", want: "Joe Bloggs"},
+		{author: "jane", want: "jane"},
+	}
+
+	for _, tt := range tests {
+		if got := m.Canonical(tt.author); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.author, got, tt.want)
+		}
+	}
+
+	if _, err := ParseMailmap(strings.NewReader("Jane Doe\n")); err == nil {
+		t.Error("ParseMailmap() error = nil for a line without an email")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	mailmap, err := ParseMailmap(strings.NewReader("John Doe <jdoe@example.com>\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := Parse(strings.NewReader("jdoe: John Doe\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New()
+	m.Merge(mailmap)
+	m.Merge(aliases)
+
+	if got := m.Canonical("jdoe@example.com"); got != "jdoe" {
+		t.Errorf("Canonical() = %q, want %q", got, "jdoe")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, MailmapName), []byte("Jane Doe <jane@example.com>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := Find(sub, MailmapName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, MailmapName); path != want {
+		t.Fatalf("Find() = %q, want %q", path, want)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Canonical("jane@example.com"); got != "Jane Doe" {
+		t.Errorf("Canonical() = %q, want %q", got, "Jane Doe")
+	}
+
+	if path, err := Find(sub, FileName); err != nil || path != "" {
+		t.Errorf("Find() = %q, %v, want no file", path, err)
+	}
+}
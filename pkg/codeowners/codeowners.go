@@ -0,0 +1,230 @@
+// Package codeowners parses GitHub and GitLab CODEOWNERS files and finds the
+// owners of a path.
+package codeowners
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locations are the paths, relative to the repository root, where a
+// CODEOWNERS file is looked for, in order.
+var Locations = []string{"CODEOWNERS", ".github/CODEOWNERS", ".gitlab/CODEOWNERS", "docs/CODEOWNERS"}
+
+var errPattern = errors.New("invalid CODEOWNERS pattern")
+
+// sectionRegex matches a GitLab section header such as "[Docs]",
+// "^[Optional]" or "[Reviews][2] @default-owner".
+var sectionRegex = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?\s*(.*)$`)
+
+// Rule is a pattern and the owners of the paths it matches.
+type Rule struct {
+	Pattern string
+	Owners  []string
+	// Section is the GitLab section the rule is in, or empty.
+	Section string
+	re      *regexp.Regexp
+}
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Rules []Rule
+}
+
+// Parse parses a CODEOWNERS file. Rules without owners take the default
+// owners of their GitLab section, if any, and otherwise remove the owners of
+// the paths they match.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{Rules: []Rule{}}
+	section := ""
+	defaults := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := sectionRegex.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			defaults = strings.Fields(stripComment(m[2]))
+			continue
+		}
+
+		fields := strings.Fields(stripComment(line))
+		if len(fields) == 0 {
+			continue
+		}
+
+		pattern := strings.ReplaceAll(fields[0], `\#`, "#")
+		re, err := compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		owners := fields[1:]
+		if len(owners) == 0 {
+			owners = defaults
+		}
+
+		f.Rules = append(f.Rules, Rule{Pattern: pattern, Owners: owners, Section: section, re: re})
+	}
+
+	return f, scanner.Err()
+}
+
+// stripComment removes a trailing # comment. A # that isn't at the start or
+// after whitespace, such as an escaped \#, is kept.
+func stripComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// compile converts a gitignore-style pattern into a regular expression
+// matching slash-separated paths relative to the repository root. A pattern
+// with a slash at the start or in the middle is anchored to the root,
+// otherwise it matches at any depth. A pattern also matches everything below
+// the directories it matches, unless it ends with a single *.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") {
+		return nil, fmt.Errorf("%w: negation is not supported: %s", errPattern, pattern)
+	}
+
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case trimmed[i] == '*':
+			b.WriteString("[^/]*")
+		case trimmed[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(trimmed[i : i+1]))
+		}
+	}
+	// As documented by GitHub, docs/* matches the files in docs but not
+	// those in its subdirectories.
+	if strings.HasSuffix(trimmed, "*") && !strings.HasSuffix(trimmed, "**") {
+		b.WriteString("$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(b.String())
+}
+
+// Owners returns the owners of a slash-separated path relative to the
+// repository root. The last matching rule wins. With GitLab sections the
+// last matching rule of each section wins and the owners of all sections are
+// combined, without duplicates.
+func (f *File) Owners(file string) []string {
+	file = strings.TrimPrefix(path.Clean(filepath.ToSlash(file)), "/")
+
+	sections := []string{}
+	matches := map[string]Rule{}
+	for _, rule := range f.Rules {
+		if !rule.re.MatchString(file) {
+			continue
+		}
+		if _, ok := matches[rule.Section]; !ok {
+			sections = append(sections, rule.Section)
+		}
+		matches[rule.Section] = rule
+	}
+
+	owners := []string{}
+	seen := map[string]bool{}
+	for _, section := range sections {
+		for _, owner := range matches[section].Owners {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+
+	return owners
+}
+
+// Find walks up from dir looking for a CODEOWNERS file in the Locations of
+// each directory and returns its path. An empty path is returned if none is
+// found.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		for _, location := range Locations {
+			file := filepath.Join(dir, filepath.FromSlash(location))
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Root returns the directory that the patterns of the CODEOWNERS file at
+// path are relative to: the parent of a .github, .gitlab or docs directory
+// holding it, or else its own directory.
+func Root(path string) string {
+	dir := filepath.Dir(path)
+	switch filepath.Base(dir) {
+	case ".github", ".gitlab", "docs":
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// Load parses the CODEOWNERS file at path.
+func Load(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
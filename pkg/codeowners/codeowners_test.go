@@ -0,0 +1,117 @@
+package codeowners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOwners(t *testing.T) {
+	f, err := Parse(strings.NewReader(`
+# Default owners
+*                   @acme/core
+*.js                @acme/web   # inline comment
+/build/logs/        @ops
+docs/*              docs@example.com
+apps/               @acme/apps
+**/testdata/**      @qa
+/api/internal       @acme/api
+\#notes.txt         @notes
+/vendor/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "main.go", want: []string{"@acme/core"}},
+		{path: "web/app.js", want: []string{"@acme/web"}},
+		{path: "build/logs/2024/out.txt", want: []string{"@ops"}},
+		{path: "src/build/logs/out.txt", want: []string{"@acme/core"}},
+		{path: "docs/index.md", want: []string{"docs@example.com"}},
+		{path: "docs/api/index.md", want: []string{"@acme/core"}},
+		{path: "src/apps/x/main.go", want: []string{"@acme/apps"}},
+		{path: "pkg/testdata/a/b.txt", want: []string{"@qa"}},
+		{path: "api/internal/db/db.go", want: []string{"@acme/api"}},
+		{path: "api/internals.go", want: []string{"@acme/core"}},
+		{path: "#notes.txt", want: []string{"@notes"}},
+		{path: "vendor/lib/lib.go", want: []string{}},
+		{path: "./main.go", want: []string{"@acme/core"}},
+	}
+
+	for _, tt := range tests {
+		if got := f.Owners(tt.path); !cmp.Equal(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOwnersSections(t *testing.T) {
+	f, err := Parse(strings.NewReader(`
+[Backend] @acme/backend
+*.go
+/internal/db/ @dba
+
+^[Docs][2] @writers
+*.md
+*.go @acme/backend
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "main.go", want: []string{"@acme/backend"}},
+		{path: "internal/db/db.go", want: []string{"@dba", "@acme/backend"}},
+		{path: "README.md", want: []string{"@writers"}},
+		{path: "Makefile", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := f.Owners(tt.path); !cmp.Equal(got, tt.want) {
+			t.Errorf("Owners(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseNegation(t *testing.T) {
+	if _, err := Parse(strings.NewReader("!*.go @a\n")); err == nil {
+		t.Error("Parse() error = nil for a negated pattern")
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join(dir, ".github", "CODEOWNERS")
+	if err := os.WriteFile(want, []byte("* @a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Find(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Find() = %q, want %q", got, want)
+	}
+	if root := Root(got); root != dir {
+		t.Errorf("Root() = %q, want %q", root, dir)
+	}
+}
@@ -0,0 +1,98 @@
+// Package duration parses human friendly durations such as "90d" or "6mo".
+package duration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day   = 24 * time.Hour
+	week  = 7 * day
+	month = 30 * day
+	year  = 365 * day
+)
+
+var (
+	errInvalid = errors.New("invalid duration")
+)
+
+// units are the calendar units accepted in addition to those of time.ParseDuration.
+// Longer suffixes are listed first so "mo" is not read as "m".
+var units = []struct {
+	suffix string
+	size   time.Duration
+}{
+	{"mo", month},
+	{"d", day},
+	{"w", week},
+	{"y", year},
+}
+
+// Parse parses a duration. In addition to the units accepted by
+// time.ParseDuration it accepts whole numbers of days (d), weeks (w),
+// months (mo, 30 days) and years (y, 365 days), e.g. "90d" or "6mo".
+func Parse(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: empty string", errInvalid)
+	}
+
+	for _, unit := range units {
+		if !strings.HasSuffix(s, unit.suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(s, unit.suffix))
+		if err != nil {
+			break
+		}
+
+		return time.Duration(n) * unit.size, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", errInvalid, s)
+	}
+
+	return d, nil
+}
+
+// Duration is a time.Duration that is read from and written to JSON as a
+// string accepted by Parse.
+type Duration time.Duration
+
+// String returns the duration in whole days, or in the format of
+// time.Duration if it is not a whole number of days.
+func (d Duration) String() string {
+	if d != 0 && time.Duration(d)%day == 0 {
+		return strconv.FormatInt(int64(time.Duration(d)/day), 10) + "d"
+	}
+	return time.Duration(d).String()
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: %s", errInvalid, data)
+	}
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,69 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameLine holds the blame information for a single line of a file.
+type BlameLine struct {
+	Commit string
+	Author string
+	Email  string
+	Time   time.Time
+}
+
+// Blame returns the blame information for each line of the file at path,
+// indexed by line number starting at 0.
+func Blame(path string) ([]BlameLine, error) {
+	out, err := Run(filepath.Dir(path), "blame", "--line-porcelain", "--", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBlame(out), nil
+}
+
+// parseBlame parses the output of git blame --line-porcelain.
+func parseBlame(out []byte) []BlameLine {
+	lines := []BlameLine{}
+	current := BlameLine{}
+	header := true
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if header {
+			current = BlameLine{Commit: strings.SplitN(line, " ", 2)[0]}
+			header = false
+			continue
+		}
+
+		// The content line is prefixed with a tab and ends the entry.
+		if strings.HasPrefix(line, "\t") {
+			lines = append(lines, current)
+			header = true
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.Author = value
+		case "author-mail":
+			current.Email = strings.Trim(value, "<>")
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.Time = time.Unix(sec, 0)
+			}
+		}
+	}
+
+	return lines
+}
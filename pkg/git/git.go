@@ -0,0 +1,43 @@
+// Package git runs git commands and parses their output.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+var (
+	errGit = errors.New("git command failed")
+)
+
+// Run runs git with args in dir and returns its standard output.
+func Run(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("%w: git %s: %s", errGit, strings.Join(args, " "), msg)
+	}
+
+	return out, nil
+}
+
+// Root returns the top-level directory of the working tree containing dir.
+func Root(dir string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,160 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TreeEntry is a file in a git tree.
+type TreeEntry struct {
+	Mode string
+	Hash string
+	Path string
+}
+
+// RevBefore returns the last commit reachable from rev, following only first
+// parents, that was committed before t. An empty hash is returned if there is
+// no such commit.
+func RevBefore(dir string, rev string, t time.Time) (string, error) {
+	out, err := Run(dir, "rev-list", "-1", "--first-parent", "--before="+t.Format(time.RFC3339), rev, "--")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ResolveRev returns the hash of the commit that rev names.
+func ResolveRev(dir string, rev string) (string, error) {
+	out, err := Run(dir, "rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ListTree returns the files in the tree of a commit. When dir is inside the
+// repository, only the files below dir are listed, with paths relative to it.
+// Submodules and symbolic links are skipped.
+func ListTree(dir string, commit string) ([]TreeEntry, error) {
+	out, err := Run(dir, "ls-tree", "-r", "-z", commit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []TreeEntry{}
+	for _, record := range bytes.Split(out, []byte{0}) {
+		if len(record) == 0 {
+			continue
+		}
+
+		// Each record is "<mode> SP <type> SP <hash> TAB <path>".
+		meta, path, ok := strings.Cut(string(record), "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("%w: unexpected ls-tree output %q", errGit, record)
+		}
+		if fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+
+		entries = append(entries, TreeEntry{Mode: fields[0], Hash: fields[2], Path: path})
+	}
+
+	return entries, nil
+}
+
+// ReadBlobs reads the content of the blobs with the given hashes with a single
+// git cat-file process, calling fn with each hash and content in order.
+func ReadBlobs(dir string, hashes []string, fn func(hash string, data []byte) error) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	go func() {
+		w := bufio.NewWriter(stdin)
+		for _, hash := range hashes {
+			fmt.Fprintln(w, hash)
+		}
+		w.Flush()
+		stdin.Close()
+	}()
+
+	readErr := readBatch(bufio.NewReader(stdout), hashes, fn)
+	if readErr != nil {
+		// Drain the output so git can exit.
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+
+	waitErr := cmd.Wait()
+	if readErr != nil {
+		return readErr
+	}
+	if waitErr != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = waitErr.Error()
+		}
+		return fmt.Errorf("%w: git cat-file --batch: %s", errGit, msg)
+	}
+
+	return nil
+}
+
+// readBatch parses the output of git cat-file --batch.
+func readBatch(r *bufio.Reader, hashes []string, fn func(hash string, data []byte) error) error {
+	for _, hash := range hashes {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("%w: reading blob %s: %v", errGit, hash, err)
+		}
+
+		// The header is "<hash> <type> <size>", or "<hash> missing".
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return fmt.Errorf("%w: blob %s: %s", errGit, hash, strings.TrimSpace(header))
+		}
+
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("%w: invalid cat-file header %q", errGit, header)
+		}
+
+		data := make([]byte, size+1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("%w: reading blob %s: %v", errGit, hash, err)
+		}
+
+		if err := fn(hash, data[:size]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package policy
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/euforic/todos/pkg/git"
+	"github.com/euforic/todos/todos"
+)
+
+// BlameAge returns an AgeFunc that uses git blame to find when the line
+// holding a comment was last changed. Each file is blamed at most once.
+// Lines that git cannot blame, such as untracked files, have a zero time.
+func BlameAge() AgeFunc {
+	var mu sync.Mutex
+	cache := map[string][]git.BlameLine{}
+
+	return func(comment todos.Comment) (time.Time, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		lines, ok := cache[comment.File]
+		if !ok {
+			// Files outside of a repository or not yet committed have no age.
+			lines, _ = git.Blame(comment.File)
+			cache[comment.File] = lines
+		}
+
+		if comment.Line < 1 || comment.Line > len(lines) {
+			return time.Time{}, nil
+		}
+
+		line := lines[comment.Line-1]
+		if line.Commit == "" || strings.Trim(line.Commit, "0") == "" {
+			return time.Time{}, nil
+		}
+
+		return line.Time, nil
+	}
+}
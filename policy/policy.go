@@ -0,0 +1,283 @@
+// Package policy checks comments against a set of configurable rules.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/euforic/todos/pkg/duration"
+	"github.com/euforic/todos/todos"
+)
+
+var errNoTracker = errors.New("checking issues needs an issue tracker")
+
+// DefaultExitCode is the exit code of a violation whose rule does not set one.
+const DefaultExitCode = 1
+
+// Rule is a policy rule. Types, Paths, Owners and Severity select the
+// comments the rule applies to, every other field is a check performed on
+// those comments. Severity selects the comments of that severity or higher.
+type Rule struct {
+	Name             string            `json:"name"`
+	Types            []string          `json:"types,omitempty"`
+	Paths            []string          `json:"paths,omitempty"`
+	Owners           []string          `json:"owners,omitempty"`
+	Severity         string            `json:"severity,omitempty"`
+	Max              *int              `json:"max,omitempty"`
+	RequireAuthor    bool              `json:"require_author,omitempty"`
+	RequireIssue     bool              `json:"require_issue,omitempty"`
+	BannedAuthors    []string          `json:"banned_authors,omitempty"`
+	BannedTypes      []string          `json:"banned_types,omitempty"`
+	MaxAge           duration.Duration `json:"max_age,omitempty"`
+	RequireOpenIssue bool              `json:"require_open_issue,omitempty"`
+	ExitCode         int               `json:"exit_code,omitempty"`
+}
+
+// Violation is a comment, or group of comments, that breaks a rule. A
+// violation with a zero ExitCode is a warning, such as an issue reference
+// that couldn't be checked, and doesn't fail the check.
+type Violation struct {
+	Rule     string         `json:"rule"`
+	Message  string         `json:"message"`
+	ExitCode int            `json:"exit_code"`
+	Comment  *todos.Comment `json:"comment,omitempty"`
+}
+
+// AgeFunc returns the time the line holding a comment was last changed.
+type AgeFunc func(comment todos.Comment) (time.Time, error)
+
+// Issue states returned by an IssueFunc.
+const (
+	IssueOpen    = "open"
+	IssueClosed  = "closed"
+	IssueMissing = "missing"
+	// IssueUnknown is the state of references that can't be checked, such
+	// as references to another tracker.
+	IssueUnknown = ""
+)
+
+// IssueFunc returns the state of the issue with the given ID.
+type IssueFunc func(id string) (string, error)
+
+// Options configures a policy check.
+type Options struct {
+	// Now is the time comment ages are measured against. Defaults to time.Now().
+	Now time.Time
+	// Age is used by rules with a MaxAge. Defaults to BlameAge().
+	Age AgeFunc
+	// Issue is used by rules with RequireOpenIssue, which fail without it.
+	Issue IssueFunc
+}
+
+// Check checks comments against rules and returns the violations found,
+// ordered by rule and then by file and line.
+func Check(comments []todos.Comment, rules []Rule, opts Options) ([]Violation, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	sorted := append([]todos.Comment{}, comments...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].File == sorted[j].File {
+			return sorted[i].Line < sorted[j].Line
+		}
+		return sorted[i].File < sorted[j].File
+	})
+
+	violations := []Violation{}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule%d", i+1)
+		}
+		if rule.ExitCode == 0 {
+			rule.ExitCode = DefaultExitCode
+		}
+		if rule.MaxAge != 0 && opts.Age == nil {
+			opts.Age = BlameAge()
+		}
+		if rule.RequireOpenIssue && opts.Issue == nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, errNoTracker)
+		}
+		if rule.Severity != "" {
+			severity, err := todos.ParseSeverity(rule.Severity)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", rule.Name, err)
+			}
+			rule.Severity = severity
+		}
+
+		ruleViolations, err := rule.check(sorted, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+		violations = append(violations, ruleViolations...)
+	}
+
+	return violations, nil
+}
+
+// ExitCode returns the highest exit code of violations, or 0 if there are none.
+func ExitCode(violations []Violation) int {
+	code := 0
+	for _, v := range violations {
+		if v.ExitCode > code {
+			code = v.ExitCode
+		}
+	}
+	return code
+}
+
+// check checks the comments, sorted by file and line, against the rule.
+func (r Rule) check(comments []todos.Comment, opts Options) ([]Violation, error) {
+	violations := []Violation{}
+
+	selected := []todos.Comment{}
+	for _, comment := range comments {
+		if r.applies(comment) {
+			selected = append(selected, comment)
+		}
+	}
+
+	if r.Max != nil && len(selected) > *r.Max {
+		violations = append(violations, r.violation(nil, "%d comments found, max is %d", len(selected), *r.Max))
+	}
+
+	for i := range selected {
+		comment := &selected[i]
+
+		if r.RequireAuthor && comment.Author == "" {
+			violations = append(violations, r.violation(comment, "%s has no author", comment.Type))
+		}
+
+		if r.RequireIssue && comment.Issue == "" {
+			violations = append(violations, r.violation(comment, "%s has no issue reference", comment.Type))
+		}
+
+		for _, author := range todos.Authors(*comment) {
+			if containsFold(r.BannedAuthors, author) {
+				violations = append(violations, r.violation(comment, "author %q is not allowed", author))
+			}
+		}
+
+		if containsFold(r.BannedTypes, comment.Type) {
+			violations = append(violations, r.violation(comment, "type %s is not allowed", comment.Type))
+		}
+
+		if r.MaxAge != 0 {
+			changed, err := opts.Age(*comment)
+			if err != nil {
+				return nil, err
+			}
+
+			if !changed.IsZero() && opts.Now.Sub(changed) > time.Duration(r.MaxAge) {
+				violations = append(violations, r.violation(comment, "%s is older than %s (last changed %s)", comment.Type, r.MaxAge, changed.Format("2006-01-02")))
+			}
+		}
+
+		if r.RequireOpenIssue && comment.Issue != "" {
+			state, err := opts.Issue(comment.Issue)
+			if err != nil {
+				return nil, err
+			}
+
+			switch state {
+			case IssueClosed:
+				violations = append(violations, r.violation(comment, "%s refers to closed issue %s", comment.Type, comment.Issue))
+			case IssueMissing:
+				violations = append(violations, r.violation(comment, "%s refers to issue %s, which does not exist", comment.Type, comment.Issue))
+			case IssueUnknown:
+				v := r.violation(comment, "%s refers to %s, which the tracker can't check", comment.Type, comment.Issue)
+				v.ExitCode = 0
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// applies reports whether the rule applies to comment.
+func (r Rule) applies(comment todos.Comment) bool {
+	if len(r.Types) > 0 && !containsFold(r.Types, comment.Type) {
+		return false
+	}
+
+	if len(r.Owners) > 0 && !ownedBy(comment, r.Owners) {
+		return false
+	}
+
+	if r.Severity != "" && todos.SeverityRank(todos.SeverityOf(comment)) < todos.SeverityRank(r.Severity) {
+		return false
+	}
+
+	if len(r.Paths) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.Paths {
+		if matchPath(pattern, comment.File) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Rule) violation(comment *todos.Comment, format string, args ...interface{}) Violation {
+	return Violation{
+		Rule:     r.Name,
+		Message:  fmt.Sprintf(format, args...),
+		ExitCode: r.ExitCode,
+		Comment:  comment,
+	}
+}
+
+// matchPath reports whether file is matched by pattern. A pattern without
+// glob characters matches the file itself and everything below it when it is
+// a directory. A glob pattern is matched against the file, its base name and
+// each of its parent directories.
+func matchPath(pattern, file string) bool {
+	pattern = strings.TrimSuffix(path.Clean(filepath.ToSlash(pattern)), "/")
+	file = path.Clean(filepath.ToSlash(file))
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return file == pattern || strings.HasPrefix(file, pattern+"/") || (pattern == "." && !path.IsAbs(file))
+	}
+
+	if matched, _ := path.Match(pattern, path.Base(file)); matched {
+		return true
+	}
+
+	for dir := file; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if matched, _ := path.Match(pattern, dir); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownedBy reports whether any of the owners owns the comment, ignoring case.
+func ownedBy(comment todos.Comment, owners []string) bool {
+	for _, owner := range comment.Owners {
+		if containsFold(owners, owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
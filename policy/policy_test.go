@@ -0,0 +1,196 @@
+package policy_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/euforic/todos/pkg/duration"
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	comments := []todos.Comment{
+		{File: "internal/db/db.go", Line: 10, Type: "FIXME", Text: "leaks connections"},
+		{File: "cmd/main.go", Line: 3, Type: "TODO", Text: "flags", Author: "bob"},
+		{File: "internal/db/db.go", Line: 2, Type: "TODO", Text: "retry #12", Author: "alice", Issue: "#12"},
+		{File: "internal/api.go", Line: 7, Type: "HACK", Text: "temporary", Author: "mallory", Severity: todos.SeverityError},
+		{File: "web/app.js", Line: 1, Type: "TODO", Text: "drop after #13", Author: "carol", Issue: "#13", Owners: []string{"@acme/web"}},
+		{File: "web/app.js", Line: 4, Type: "TODO", Text: "see #14", Author: "carol", Issue: "#14", Owners: []string{"@acme/web"}},
+		{File: "web/app.js", Line: 9, Type: "TODO", Text: "see PROJ-4", Author: "carol", Issue: "PROJ-4", Owners: []string{"@acme/web", "@qa"}},
+	}
+
+	ages := map[string]time.Time{
+		"cmd/main.go":       now.Add(-200 * 24 * time.Hour),
+		"internal/db/db.go": now.Add(-10 * 24 * time.Hour),
+	}
+	age := func(c todos.Comment) (time.Time, error) {
+		return ages[c.File], nil
+	}
+
+	states := map[string]string{"#12": policy.IssueOpen, "#13": policy.IssueClosed, "#14": policy.IssueMissing}
+	issue := func(id string) (string, error) {
+		return states[id], nil
+	}
+
+	tests := []struct {
+		name  string
+		rules []policy.Rule
+		want  []policy.Violation
+	}{
+		{
+			name:  "NoRules",
+			rules: nil,
+			want:  []policy.Violation{},
+		},
+		{
+			name:  "PerTypeLimit",
+			rules: []policy.Rule{{Name: "no-fixme", Types: []string{"fixme"}, Max: intPtr(0), ExitCode: 3}},
+			want: []policy.Violation{
+				{Rule: "no-fixme", Message: "1 comments found, max is 0", ExitCode: 3},
+			},
+		},
+		{
+			name: "PerDirectoryLimit",
+			rules: []policy.Rule{
+				{Name: "internal", Paths: []string{"internal/"}, Max: intPtr(2)},
+				{Name: "db", Paths: []string{"internal/db"}, Max: intPtr(2)},
+				{Name: "cmd", Paths: []string{"cmd/*.go"}, Max: intPtr(1)},
+			},
+			want: []policy.Violation{
+				{Rule: "internal", Message: "3 comments found, max is 2", ExitCode: 1},
+			},
+		},
+		{
+			name:  "RequireAuthorAndIssue",
+			rules: []policy.Rule{{Name: "owned", Types: []string{"TODO", "FIXME"}, RequireAuthor: true, RequireIssue: true}},
+			want: []policy.Violation{
+				{Rule: "owned", Message: "TODO has no issue reference", ExitCode: 1, Comment: &comments[1]},
+				{Rule: "owned", Message: "FIXME has no author", ExitCode: 1, Comment: &comments[0]},
+				{Rule: "owned", Message: "FIXME has no issue reference", ExitCode: 1, Comment: &comments[0]},
+			},
+		},
+		{
+			name:  "Banned",
+			rules: []policy.Rule{{BannedAuthors: []string{"Mallory"}, BannedTypes: []string{"HACK"}}},
+			want: []policy.Violation{
+				{Rule: "rule1", Message: `author "mallory" is not allowed`, ExitCode: 1, Comment: &comments[3]},
+				{Rule: "rule1", Message: "type HACK is not allowed", ExitCode: 1, Comment: &comments[3]},
+			},
+		},
+		{
+			name:  "MaxAge",
+			rules: []policy.Rule{{Name: "stale", MaxAge: duration.Duration(90 * 24 * time.Hour)}},
+			want: []policy.Violation{
+				{Rule: "stale", Message: "TODO is older than 90d (last changed 2022-11-13)", ExitCode: 1, Comment: &comments[1]},
+			},
+		},
+		{
+			name:  "PerOwner",
+			rules: []policy.Rule{{Name: "web", Owners: []string{"@ACME/web"}, Max: intPtr(1)}},
+			want: []policy.Violation{
+				{Rule: "web", Message: "3 comments found, max is 1", ExitCode: 1},
+			},
+		},
+		{
+			name: "MinSeverity",
+			rules: []policy.Rule{
+				{Name: "warnings", Severity: "warning", Max: intPtr(1)},
+				{Name: "errors", Severity: "ERROR", Max: intPtr(0)},
+			},
+			want: []policy.Violation{
+				{Rule: "warnings", Message: "2 comments found, max is 1", ExitCode: 1},
+				{Rule: "errors", Message: "1 comments found, max is 0", ExitCode: 1},
+			},
+		},
+		{
+			name:  "RequireOpenIssue",
+			rules: []policy.Rule{{Name: "issues", RequireOpenIssue: true}},
+			want: []policy.Violation{
+				{Rule: "issues", Message: "TODO refers to closed issue #13", ExitCode: 1, Comment: &comments[4]},
+				{Rule: "issues", Message: "TODO refers to issue #14, which does not exist", ExitCode: 1, Comment: &comments[5]},
+				{Rule: "issues", Message: "TODO refers to PROJ-4, which the tracker can't check", ExitCode: 0, Comment: &comments[6]},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.Check(comments, tt.rules, policy.Options{Now: now, Age: age, Issue: issue})
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Check() \n%s", cmp.Diff(got, tt.want))
+			}
+		})
+	}
+	if _, err := policy.Check(comments, []policy.Rule{{RequireOpenIssue: true}}, policy.Options{Now: now}); err == nil {
+		t.Error("Check() without an issue tracker error = nil")
+	}
+	if _, err := policy.Check(comments, []policy.Rule{{Severity: "critical"}}, policy.Options{Now: now}); err == nil {
+		t.Error("Check() with an unknown severity error = nil")
+	}
+}
+
+func TestCheckPatternIssues(t *testing.T) {
+	m, err := todos.NewMatcher(todos.MatchOptions{
+		Patterns: []todos.Pattern{{Regex: `(?P<type>TODO)-(?P<issue>\d+):\s*(?P<text>.*)`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments, err := m.Parse(strings.NewReader("// TODO-5: drop the shim\n"), "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(id string) (string, error) {
+		if id == "#5" {
+			return policy.IssueClosed, nil
+		}
+		return policy.IssueUnknown, nil
+	}
+
+	got, err := policy.Check(comments, []policy.Rule{{Name: "issues", RequireOpenIssue: true}}, policy.Options{Issue: issue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Message != "TODO refers to closed issue #5" {
+		t.Errorf("Check() = %+v, want the closed issue #5", got)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	violations := []policy.Violation{
+		{Rule: "a", Message: "first", ExitCode: 1},
+		{Rule: "b", Message: "second", ExitCode: 4},
+	}
+
+	var buf bytes.Buffer
+	if err := policy.WriteJSON(&buf, violations); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	var got policy.Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := policy.Report{Violations: violations, ExitCode: 4}
+	if !cmp.Equal(got, want) {
+		t.Errorf("WriteJSON() \n%s", cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Report is the machine readable result of a policy check.
+type Report struct {
+	Violations []Violation `json:"violations"`
+	ExitCode   int         `json:"exit_code"`
+}
+
+// WriteJSON writes the violations to the io.Writer as a JSON report
+func WriteJSON(w io.Writer, violations []Violation) error {
+	if violations == nil {
+		violations = []Violation{}
+	}
+
+	report := Report{
+		Violations: violations,
+		ExitCode:   ExitCode(violations),
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+// WriteText writes the violations to the io.Writer, one per line
+func WriteText(w io.Writer, violations []Violation) error {
+	for _, v := range violations {
+		var err error
+		if v.Comment != nil {
+			_, err = fmt.Fprintf(w, "%s:%d: %s: %s\n", v.Comment.File, v.Comment.Line, v.Rule, v.Message)
+		} else {
+			_, err = fmt.Fprintf(w, "%s: %s\n", v.Rule, v.Message)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
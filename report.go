@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/todos"
+)
+
+var reportCommand = &command{
+	name:    "report",
+	usage:   "report [options] <file.json|->",
+	summary: "Print comments saved with '-output json' in another output style",
+	run:     runReport,
+}
+
+var errNoReportFile = errors.New("a JSON file produced by 'todos -output json' is required ('-' for stdin)")
+
+// runReport runs the report command
+func runReport(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := &searchFlags{}
+	defineOutputFlags(fs, flags)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errNoReportFile
+	}
+
+	comments, err := readComments(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Default()
+	cfg.Output = flags.outputStyle
+	cfg.SortBy = flags.sortBy
+	cfg.GroupBy = flags.groupBy
+	cfg.Format = flags.format
+
+	return outputComments(cfg, comments)
+}
+
+// readComments reads comments written as JSON from path, or from stdin if path is "-"
+func readComments(path string) ([]todos.Comment, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// WriteJSON writes nothing when there are no comments.
+	comments := []todos.Comment{}
+	if len(data) == 0 {
+		return comments, nil
+	}
+
+	if err := json.Unmarshal(data, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
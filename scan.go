@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+)
+
+var scanCommand = &command{
+	name:    "scan",
+	usage:   "scan [options] [path...]",
+	summary: "Search directories and files for comments and print them",
+	run:     runScan,
+}
+
+var errStdinPaths = errors.New("-stdin cannot be combined with paths or -files-from")
+
+// runScan runs the scan command
+func runScan(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	defineStdinFlags(fs, flags)
+	defineOutputFlags(fs, flags)
+	fs.IntVar(&flags.validateMax, "validate-max", 0, "Validate that the number of comments is less than or equal to the max")
+	fs.StringVar(&flags.failOn, "fail-on", "", "Exit with status 1 if any comment has this severity or higher (info, warning, error)")
+	watch := defineWatchFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if watch.enabled {
+		return watchComments(fs, flags, watch)
+	}
+
+	cfg, comments, err := findComments(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	if cfg.ValidateMax > 0 {
+		if err := checkPolicies(comments, validateMaxRules(cfg), policy.Options{}, "text", ""); err != nil {
+			return err
+		}
+	}
+
+	if err := outputComments(cfg, comments); err != nil {
+		return err
+	}
+
+	// Unlike -validate-max, -fail-on gates CI on the comments written, so
+	// they are output first.
+	if rules := failOnRules(cfg); len(rules) > 0 {
+		return checkPolicies(comments, rules, policy.Options{}, "text", "")
+	}
+
+	return nil
+}
+
+// searchFlags holds the values of the command line flags shared by the commands
+type searchFlags struct {
+	configFile   string
+	noConfig     bool
+	ignores      string
+	sortBy       string
+	groupBy      string
+	commentTypes string
+	searchHidden bool
+	permissive   bool
+	validateMax  int
+	failOn       string
+	outputStyle  string
+	format       string
+	noGitignore  bool
+	filesFrom    string
+	stdin        bool
+	stdinName    string
+	cache        string
+	codeOwners   string
+	authorsFile  string
+
+	caseSensitive  bool
+	minTextLength  int
+	requireComment bool
+
+	filter        string
+	filterTypes   string
+	filterAuthors string
+	filterPaths   string
+	textContains  string
+}
+
+// defineSearchFlags defines the flags that control config loading and which
+// comments are found
+func defineSearchFlags(fs *flag.FlagSet) *searchFlags {
+	v := &searchFlags{}
+	fs.StringVar(&v.configFile, "config", "", "Path to a config file (default: search for .todos.yml, .todos.yaml, .todos.toml or .todos.json from dir upward)")
+	fs.BoolVar(&v.noConfig, "no-config", false, "Do not load a config file")
+	fs.StringVar(&v.ignores, "ignore", "", "Comma-separated list of files and directories to ignore")
+	fs.StringVar(&v.commentTypes, "types", "TODO,FIXME", "Comma-separated list of comment types to search for")
+	fs.BoolVar(&v.searchHidden, "hidden", false, "Search hidden files and directories")
+	fs.BoolVar(&v.permissive, "permissive", false, "Permissive mode (looser regex, but can match more than intended, strict format is 'TYPE(authors): text' where authors are optional)")
+	fs.BoolVar(&v.caseSensitive, "case-sensitive", false, "Match comment types in the case they are written in, so TODO doesn't match 'todo'")
+	fs.IntVar(&v.minTextLength, "min-text-length", 0, "Skip comments whose text is shorter than this many characters")
+	fs.BoolVar(&v.requireComment, "require-comment", false, "Only match comment types inside comments of the file's language, not in code")
+	fs.BoolVar(&v.noGitignore, "no-gitignore", false, "Ignore .gitignore file")
+	fs.StringVar(&v.filesFrom, "files-from", "", "Read a newline or NUL separated list of files to search from this file ('-' for stdin)")
+	fs.StringVar(&v.cache, "cache", "", "Cache parsed comments in this file and only parse files that changed since the last run (e.g. .todos-cache)")
+	fs.StringVar(&v.codeOwners, "codeowners", "", "CODEOWNERS file that assigns owners to comments (default: search for CODEOWNERS, .github/CODEOWNERS, .gitlab/CODEOWNERS or docs/CODEOWNERS from dir upward)")
+	fs.StringVar(&v.authorsFile, "authors-file", "", "File mapping author aliases to canonical names (default: search for .todos-authors from dir upward)")
+	return v
+}
+
+// defineStdinFlags defines the flags for searching content read from stdin
+func defineStdinFlags(fs *flag.FlagSet, v *searchFlags) {
+	fs.BoolVar(&v.stdin, "stdin", false, "Search the content of stdin instead of files")
+	fs.StringVar(&v.stdinName, "stdin-filename", "", "File name to report and detect the language from for content read with -stdin")
+}
+
+// defineOutputFlags defines the flags that control how comments are printed
+func defineOutputFlags(fs *flag.FlagSet, v *searchFlags) {
+	fs.StringVar(&v.sortBy, "sortby", "", "Comma-separated fields to sort results by (author, file, line, type, text, issue, language, owner, severity), each optionally postfixed with ':desc' (e.g. type,author:desc)")
+	fs.StringVar(&v.groupBy, "groupby", "", "Comma-separated fields to group table, group and md output by, outermost first (file, author, type, dir, tag, owner, severity)")
+	fs.StringVar(&v.outputStyle, "output", "table", "Output style (table, group, json, md, sarif, checkstyle, github)")
+	fs.StringVar(&v.format, "format", "", "Go template string to use for output style (-output will be ignored if format is set)")
+}
+
+// findComments loads the config and finds the comments in the paths, or in
+// stdin, selected by the flags
+func findComments(fs *flag.FlagSet, flags *searchFlags) (*config.Config, []todos.Comment, error) {
+	if flags.stdin {
+		if fs.NArg() > 0 || flags.filesFrom != "" {
+			return nil, nil, errStdinPaths
+		}
+
+		cfg, err := loadConfig(fs, flags, filepath.Dir(flags.stdinName))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		comments, err := parseStdin(cfg, flags.stdinName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := annotateComments(cfg, filepath.Dir(flags.stdinName), comments); err != nil {
+			return nil, nil, err
+		}
+
+		comments, err = filterComments(cfg, comments)
+		return cfg, comments, err
+	}
+
+	paths, err := searchPaths(fs, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := loadConfig(fs, flags, configDir(paths))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comments, err := searchComments(cfg, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	comments, err = filterComments(cfg, comments)
+	return cfg, comments, err
+}
+
+// parseStdin parses the content of stdin as the file name, which does not
+// need to exist on disk
+func parseStdin(cfg *config.Config, name string) ([]todos.Comment, error) {
+	if name == "" {
+		name = "<stdin>"
+	}
+
+	m, err := newMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := m.Parse(os.Stdin, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if comments == nil {
+		comments = []todos.Comment{}
+	}
+
+	return comments, nil
+}
+
+// searchDir returns the directory argument, defaulting to the current directory
+func searchDir(fs *flag.FlagSet) string {
+	if dir := fs.Arg(0); dir != "" {
+		return dir
+	}
+	return "."
+}
+
+// searchPaths returns the paths to search: the arguments followed by the
+// files listed in -files-from. The current directory is searched when
+// neither is given.
+func searchPaths(fs *flag.FlagSet, flags *searchFlags) ([]string, error) {
+	paths := append([]string{}, fs.Args()...)
+
+	if flags.filesFrom != "" {
+		files, err := readFileList(flags.filesFrom)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			// Lists such as the output of git diff include deleted files.
+			if _, err := os.Stat(file); os.IsNotExist(err) {
+				continue
+			}
+			paths = append(paths, file)
+		}
+
+		// An empty list searches nothing rather than the current directory.
+		return paths, nil
+	}
+
+	if len(paths) == 0 {
+		paths = append(paths, ".")
+	}
+
+	return paths, nil
+}
+
+// configDir returns the path the config file is searched for from
+func configDir(paths []string) string {
+	if len(paths) == 0 {
+		return "."
+	}
+	return paths[0]
+}
+
+// readFileList reads a list of files separated by newlines, or by NUL
+// characters if the list contains any, from path or from stdin if path is "-"
+func readFileList(path string) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "\n"
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = "\x00"
+	}
+
+	files := []string{}
+	for _, file := range strings.Split(string(data), sep) {
+		file = strings.TrimSuffix(file, "\r")
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// loadConfig loads the config file for dir and applies the flags that were
+// explicitly set on top of it
+func loadConfig(fs *flag.FlagSet, flags *searchFlags, dir string) (*config.Config, error) {
+	cfg := config.Default()
+
+	path := flags.configFile
+	if path == "" && !flags.noConfig {
+		found, err := config.Find(dir)
+		if err != nil {
+			return nil, err
+		}
+		path = found
+	}
+
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "ignore":
+			cfg.Ignore = splitList(flags.ignores)
+		case "sortby":
+			cfg.SortBy = flags.sortBy
+		case "groupby":
+			cfg.GroupBy = flags.groupBy
+		case "types":
+			cfg.Types = splitList(flags.commentTypes)
+		case "hidden":
+			cfg.Hidden = flags.searchHidden
+		case "permissive":
+			cfg.Permissive = flags.permissive
+		case "case-sensitive":
+			cfg.CaseSensitive = flags.caseSensitive
+		case "min-text-length":
+			cfg.MinTextLength = flags.minTextLength
+		case "require-comment":
+			cfg.RequireComment = flags.requireComment
+		case "validate-max":
+			cfg.ValidateMax = flags.validateMax
+		case "fail-on":
+			cfg.FailOn = flags.failOn
+		case "output":
+			cfg.Output = flags.outputStyle
+		case "format":
+			cfg.Format = flags.format
+		case "no-gitignore":
+			cfg.NoGitignore = flags.noGitignore
+		case "cache":
+			cfg.Cache = flags.cache
+		case "codeowners":
+			cfg.CodeOwners = flags.codeOwners
+		case "authors-file":
+			cfg.AuthorsFile = flags.authorsFile
+		case "filter":
+			cfg.Filter = flags.filter
+		}
+	})
+
+	cfg.Filter = andFilters(cfg.Filter, flagFilter(flags))
+
+	// Report invalid patterns and severities before any work is done.
+	if _, err := newMatcher(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateSeverities(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// searchComments searches paths for comments using the config and assigns
+// their code owners, canonical authors and severities
+func searchComments(cfg *config.Config, paths []string) ([]todos.Comment, error) {
+	comments, err := scanPaths(cfg, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, annotateComments(cfg, configDir(paths), comments)
+}
+
+// newMatcher returns the matcher for the configured comment types and
+// patterns
+func newMatcher(cfg *config.Config) (*todos.Matcher, error) {
+	return todos.NewMatcher(todos.MatchOptions{
+		Types:          cfg.Types,
+		CaseSensitive:  cfg.CaseSensitive,
+		TypeAliases:    cfg.TypeAliases,
+		Permissive:     cfg.Permissive,
+		Patterns:       cfg.Patterns,
+		MinTextLength:  cfg.MinTextLength,
+		RequireComment: cfg.RequireComment,
+	})
+}
+
+// scanPaths searches paths for comments, through the cache if configured
+func scanPaths(cfg *config.Config, paths []string) ([]todos.Comment, error) {
+	m, err := newMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Cache == "" {
+		ignoreList, err := buildIgnoreList(cfg, paths)
+		if err != nil {
+			return nil, err
+		}
+		return m.SearchPaths(paths, ignoreList)
+	}
+
+	cache, err := m.OpenCache(cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCache(cfg, cache, paths)
+}
+
+// scanCache searches paths for comments through cache and saves it
+func scanCache(cfg *config.Config, cache *todos.Cache, paths []string) ([]todos.Comment, error) {
+	ignoreList, err := buildIgnoreList(cfg, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := cache.SearchPaths(paths, ignoreList)
+	if err != nil {
+		return nil, err
+	}
+
+	return comments, cache.Save()
+}
+
+// buildIgnoreList returns the configured ignore patterns and, unless
+// disabled, the patterns of the .gitignore files for paths
+func buildIgnoreList(cfg *config.Config, paths []string) ([]string, error) {
+	ignoreList := parseIgnoreList(cfg.Ignore, cfg.Hidden)
+
+	if !cfg.NoGitignore {
+		ignorePatterns, err := gitignorePatterns(paths)
+		if err != nil {
+			return nil, err
+		}
+		ignoreList = append(ignoreList, ignorePatterns...)
+	}
+
+	return ignoreList, nil
+}
+
+// gitignorePatterns returns the patterns of the .gitignore files in the
+// directories being searched, or in the current directory if only files are
+// searched
+func gitignorePatterns(paths []string) ([]string, error) {
+	dirs := []string{}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+		}
+	}
+	if len(dirs) == 0 {
+		dirs = append(dirs, ".")
+	}
+
+	patterns := []string{}
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		dirPatterns, err := todos.ParseGitignore(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		for _, pattern := range dirPatterns {
+			if !seen[pattern] {
+				seen[pattern] = true
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+
+	return patterns, nil
+}
+
+// parseIgnoreList builds the ignore list from the configured patterns
+func parseIgnoreList(ignores []string, searchHidden bool) []string {
+	ignoreList := append([]string{}, ignores...)
+
+	if !searchHidden {
+		ignoreList = append(ignoreList, ".*")
+	}
+
+	return ignoreList
+}
+
+// outputComments outputs the comments in the configured style
+func outputComments(cfg *config.Config, comments []todos.Comment) error {
+	sortBy, err := todos.ParseSortKeys(cfg.SortBy)
+	if err != nil {
+		return err
+	}
+
+	groupBy, err := todos.ParseGroupBy(cfg.GroupBy)
+	if err != nil {
+		return err
+	}
+
+	outputStyle := cfg.Output
+	if cfg.Format != "" {
+		outputStyle = "format"
+	}
+
+	switch outputStyle {
+	case "sarif":
+		return todos.WriteSARIF(os.Stdout, comments, sortBy)
+	case "checkstyle":
+		return todos.WriteCheckstyle(os.Stdout, comments, sortBy)
+	case "github":
+		return todos.WriteGitHub(os.Stdout, comments, sortBy)
+	case "group":
+		return todos.WriteFileGroup(os.Stdout, comments, sortBy, groupBy)
+	case "json":
+		return todos.WriteJSON(os.Stdout, comments, sortBy)
+	case "md":
+		return todos.WriteMarkdown(os.Stdout, comments, sortBy, groupBy)
+	case "format":
+		return todos.WriteTemplate(os.Stdout, comments, sortBy, cfg.Format)
+	default:
+		if isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" {
+			return todos.WriteColorTable(os.Stdout, comments, sortBy, groupBy)
+		}
+		return todos.WriteTable(os.Stdout, comments, sortBy, groupBy)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/euforic/todos/filter"
+	"github.com/euforic/todos/server"
+	"github.com/euforic/todos/todos"
+)
+
+var serveCommand = &command{
+	name:    "serve",
+	usage:   "serve [options] [path...]",
+	summary: "Serve comments as a JSON API and web dashboard, re-scanning when files change",
+	run:     runServe,
+}
+
+// runServe runs the serve command
+func runServe(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	interval := fs.Duration("interval", server.DefaultInterval, "Minimum time between checks for changed files")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	paths, err := searchPaths(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(fs, flags, configDir(paths))
+	if err != nil {
+		return err
+	}
+
+	ignoreList, err := buildIgnoreList(cfg, paths)
+	if err != nil {
+		return err
+	}
+
+	f, err := filter.Parse(cfg.Filter)
+	if err != nil {
+		return err
+	}
+
+	annotator, err := loadAnnotator(cfg, configDir(paths))
+	if err != nil {
+		return err
+	}
+
+	// Scan like the scan command, through the cache and filter if configured.
+	srv := server.New(server.Options{
+		Search: func() ([]todos.Comment, error) {
+			comments, err := scanPaths(cfg, paths)
+			if err != nil {
+				return nil, err
+			}
+			annotator.annotate(comments)
+			return f.Apply(comments), nil
+		},
+		Paths:    paths,
+		Ignores:  ignoreList,
+		Interval: *interval,
+	})
+
+	// Scan before listening so configuration errors are reported immediately.
+	if _, _, err := srv.Comments(true); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving todos on %s\n", *addr)
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
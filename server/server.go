@@ -0,0 +1,336 @@
+// Package server serves comments over HTTP as a JSON API and a web dashboard.
+package server
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/euforic/todos/stats"
+	"github.com/euforic/todos/todos"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// DefaultInterval is the default minimum time between checks for changed files.
+const DefaultInterval = 2 * time.Second
+
+// Options configures a Server.
+type Options struct {
+	// Search scans for comments.
+	Search func() ([]todos.Comment, error)
+	// Paths and Ignores select the files watched for changes. Changes are
+	// not detected if Paths is empty.
+	Paths   []string
+	Ignores []string
+	// Interval is the minimum time between checks for changed files.
+	// Defaults to DefaultInterval.
+	Interval time.Duration
+}
+
+// Server is an http.Handler serving the comments found by a scan. Results
+// are cached and the scan is only repeated when files change or a rescan is
+// requested.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu          sync.Mutex
+	comments    []todos.Comment
+	scannedAt   time.Time
+	checkedAt   time.Time
+	fingerprint string
+}
+
+// Stats are the comment counts returned by /api/stats, as computed by the
+// stats command, and the time the comments were scanned.
+type Stats struct {
+	stats.Stats
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// New returns a server using opts.
+func New(opts Options) *Server {
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/api/comments", s.handleComments)
+	s.mux.HandleFunc("/api/stats", s.handleStats)
+	s.mux.HandleFunc("/api/rescan", s.handleRescan)
+	s.mux.HandleFunc("/", s.handleDashboard)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Comments returns the cached comments, scanning first if there are none yet,
+// if files changed since the last scan, or if force is true.
+func (s *Server) Comments(force bool) ([]todos.Comment, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !force && !s.scannedAt.IsZero() && now.Sub(s.checkedAt) < s.opts.Interval {
+		return s.comments, s.scannedAt, nil
+	}
+	s.checkedAt = now
+
+	fingerprint := ""
+	if len(s.opts.Paths) > 0 {
+		var err error
+		fingerprint, err = s.computeFingerprint()
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	if !force && !s.scannedAt.IsZero() && fingerprint == s.fingerprint {
+		return s.comments, s.scannedAt, nil
+	}
+
+	comments, err := s.opts.Search()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		if comments[i].File == comments[j].File {
+			return comments[i].Line < comments[j].Line
+		}
+		return comments[i].File < comments[j].File
+	})
+
+	s.comments = comments
+	s.scannedAt = now
+	s.fingerprint = fingerprint
+
+	return s.comments, s.scannedAt, nil
+}
+
+// computeFingerprint hashes the names, sizes and modification times of the
+// watched files, so any added, removed or modified file changes it.
+func (s *Server) computeFingerprint() (string, error) {
+	files, err := todos.Files(s.opts.Paths, s.opts.Ignores)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// query returns the comments matching the filters in the request and the
+// time they were scanned
+func (s *Server) query(r *http.Request) ([]todos.Comment, time.Time, error) {
+	comments, scannedAt, err := s.Comments(r.URL.Query().Get("refresh") != "")
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return filter(comments, r.URL.Query()), scannedAt, nil
+}
+
+// filter returns the comments matching the type, author and file parameters.
+// Types and authors are matched ignoring case, files by path prefix. Each
+// parameter may be given more than once to match any of its values.
+func filter(comments []todos.Comment, params map[string][]string) []todos.Comment {
+	types, authors, files := nonEmpty(params["type"]), nonEmpty(params["author"]), nonEmpty(params["file"])
+
+	result := []todos.Comment{}
+	for _, c := range comments {
+		if len(types) > 0 && !anyMatch(types, func(v string) bool { return strings.EqualFold(v, c.Type) }) {
+			continue
+		}
+		if len(authors) > 0 && !anyMatch(todos.Authors(c), func(a string) bool {
+			return anyMatch(authors, func(v string) bool { return strings.EqualFold(v, a) })
+		}) {
+			continue
+		}
+		if len(files) > 0 && !anyMatch(files, func(v string) bool { return strings.HasPrefix(c.File, v) }) {
+			continue
+		}
+		result = append(result, c)
+	}
+
+	return result
+}
+
+func nonEmpty(values []string) []string {
+	result := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func anyMatch(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// stats counts the comments by directory below the watched paths.
+func (s *Server) stats(comments []todos.Comment, scannedAt time.Time) (Stats, error) {
+	st, err := stats.Compute(comments, stats.Options{Roots: s.opts.Paths})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return Stats{Stats: st, ScannedAt: scannedAt}, nil
+}
+
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+
+	comments, _, err := s.query(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, comments)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+
+	comments, scannedAt, err := s.query(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	st, err := s.stats(comments, scannedAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, st)
+}
+
+func (s *Server) handleRescan(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	comments, scannedAt, err := s.Comments(true)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	st, err := s.stats(comments, scannedAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, st)
+}
+
+// dashboard is the data rendered by the dashboard template.
+type dashboard struct {
+	Stats    Stats
+	Comments []todos.Comment
+	Filter   map[string]string
+	// NoAuthor is the name comments without an author are counted under.
+	NoAuthor string
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+
+	comments, scannedAt, err := s.query(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	st, err := s.stats(comments, scannedAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	data := dashboard{
+		Stats:    st,
+		Comments: comments,
+		NoAuthor: todos.NoAuthor,
+		Filter: map[string]string{
+			"type":   r.URL.Query().Get("type"),
+			"author": r.URL.Query().Get("author"),
+			"file":   r.URL.Query().Get("file"),
+		},
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, "index.html", data); err != nil {
+		writeError(w, err)
+	}
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
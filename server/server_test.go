@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/euforic/todos/stats"
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServer(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "internal/db.go", Line: 4, Type: "FIXME", Text: "leak", Author: "alice"},
+		{File: "cmd/main.go", Line: 1, Type: "TODO", Text: "flags"},
+		{File: "internal/api.go", Line: 9, Type: "TODO", Text: "auth", Author: "bob", Authors: []string{"bob", "dave"}},
+	}
+
+	var scans int32
+	srv := New(Options{
+		Search: func() ([]todos.Comment, error) {
+			atomic.AddInt32(&scans, 1)
+			return append([]todos.Comment{}, comments...), nil
+		},
+		Interval: time.Hour,
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want []todos.Comment
+	}{
+		{name: "All", path: "/api/comments", want: []todos.Comment{comments[1], comments[2], comments[0]}},
+		{name: "Type", path: "/api/comments?type=todo", want: []todos.Comment{comments[1], comments[2]}},
+		{name: "Author", path: "/api/comments?author=alice&author=bob", want: []todos.Comment{comments[2], comments[0]}},
+		{name: "File", path: "/api/comments?file=internal/&type=TODO", want: []todos.Comment{comments[2]}},
+		{name: "NoMatch", path: "/api/comments?author=carol", want: []todos.Comment{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(tt.path)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d", tt.path, rec.Code)
+			}
+
+			var got []todos.Comment
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("GET %s: %v", tt.path, err)
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("GET %s \n%s", tt.path, cmp.Diff(got, tt.want))
+			}
+		})
+	}
+
+	if n := atomic.LoadInt32(&scans); n != 1 {
+		t.Errorf("scans = %d, want results to be cached after the first scan", n)
+	}
+
+	var st Stats
+	if err := json.Unmarshal(get("/api/stats?refresh=1").Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	byType, byAuthor := counts(st.ByType), counts(st.ByAuthor)
+	if st.Total != 3 || st.Files != 3 || byType["TODO"] != 2 || byAuthor[todos.NoAuthor] != 1 || byAuthor["dave"] != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	if n := atomic.LoadInt32(&scans); n != 2 {
+		t.Errorf("scans = %d, want refresh to rescan", n)
+	}
+
+	rec := get("/?type=FIXME")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "internal/db.go:4") || strings.Contains(rec.Body.String(), "cmd/main.go:1") {
+		t.Errorf("dashboard status = %d body = %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := get("/api/rescan"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /api/rescan status = %d", rec.Code)
+	}
+
+	if rec := get("/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /missing status = %d", rec.Code)
+	}
+}
+
+func TestServerRescansChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("// TODO: one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := New(Options{
+		Search: func() ([]todos.Comment, error) {
+			return todos.Search(dir, []string{"TODO"}, nil, false)
+		},
+		Paths:    []string{dir},
+		Interval: time.Nanosecond,
+	})
+
+	comments, _, err := srv.Comments(false)
+	if err != nil || len(comments) != 1 {
+		t.Fatalf("Comments() = %v, %v", comments, err)
+	}
+
+	if err := os.WriteFile(file, []byte("// TODO: one\n// TODO: two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time changes on file systems with coarse timestamps.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	comments, _, err = srv.Comments(false)
+	if err != nil || len(comments) != 2 {
+		t.Fatalf("Comments() after change = %v, %v", comments, err)
+	}
+}
+
+// counts returns the counts by name.
+func counts(list []stats.Count) map[string]int {
+	result := map[string]int{}
+	for _, c := range list {
+		result[c.Name] = c.Count
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+)
+
+// validateSeverities checks the configured severities and -fail-on level and
+// normalizes them to lower case
+func validateSeverities(cfg *config.Config) error {
+	for typ, severity := range cfg.Severity {
+		level, err := todos.ParseSeverity(severity)
+		if err != nil {
+			return fmt.Errorf("severity of %s: %w", typ, err)
+		}
+		cfg.Severity[typ] = level
+	}
+
+	if cfg.FailOn != "" {
+		level, err := todos.ParseSeverity(cfg.FailOn)
+		if err != nil {
+			return fmt.Errorf("fail_on: %w", err)
+		}
+		cfg.FailOn = level
+	}
+
+	return nil
+}
+
+// failOnRules returns the rule for the -fail-on level, if set, which fails
+// when any comment has that severity or higher
+func failOnRules(cfg *config.Config) []policy.Rule {
+	if cfg.FailOn == "" {
+		return nil
+	}
+
+	zero := 0
+	return []policy.Rule{{Name: "fail-on", Severity: cfg.FailOn, Max: &zero}}
+}
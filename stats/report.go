@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// section is a titled list of counts in a report.
+type section struct {
+	title  string
+	counts []Count
+	// ordered sections keep their order instead of being cut to the top rows.
+	ordered bool
+}
+
+func (st Stats) sections() []section {
+	sections := []section{
+		{title: "Type", counts: st.ByType},
+		{title: "Author", counts: st.ByAuthor},
+		{title: "Directory", counts: st.ByDir},
+		{title: "Extension", counts: st.ByExt},
+	}
+	if len(st.ByAge) > 0 {
+		sections = append(sections, section{title: "Age", counts: st.ByAge, ordered: true})
+	}
+	return sections
+}
+
+// rows returns the counts of the section to print, keeping the top counts
+// and adding up the others if there are more than top.
+func (s section) rows(top int) []Count {
+	if s.ordered || top <= 0 || len(s.counts) <= top {
+		return s.counts
+	}
+
+	rows := append([]Count{}, s.counts[:top]...)
+	other := Count{Name: fmt.Sprintf("(%d others)", len(s.counts)-top)}
+	for _, c := range s.counts[top:] {
+		other.Count += c.Count
+	}
+	return append(rows, other)
+}
+
+// label returns the name of a count, or a placeholder for an empty name.
+func label(title, name string) string {
+	if name != "" {
+		return name
+	}
+	if title == "Extension" {
+		return "(none)"
+	}
+	return "(no " + strings.ToLower(title) + ")"
+}
+
+// summary describes the totals in a sentence.
+func (st Stats) summary(bold func(string) string) string {
+	s := fmt.Sprintf("%s comments in %s files", bold(fmt.Sprint(st.Total)), bold(fmt.Sprint(st.Files)))
+	if st.Lines > 0 {
+		s += fmt.Sprintf(", %s per 1000 lines of %d", bold(fmt.Sprintf("%.2f", st.Density)), st.Lines)
+	}
+	return s
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", float64(n)*100/float64(total))
+}
+
+// WriteTable writes the stats as plain text tables, listing at most top rows
+// per table if top is positive.
+func WriteTable(w io.Writer, st Stats, top int) error {
+	tabW := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tabW, st.summary(func(s string) string { return s }))
+
+	for _, s := range st.sections() {
+		fmt.Fprintf(tabW, "\n%s\tCount\tShare\n", s.title)
+		for _, c := range s.rows(top) {
+			fmt.Fprintf(tabW, "%s\t%d\t%s\n", label(s.title, c.Name), c.Count, percent(c.Count, st.Total))
+		}
+	}
+
+	return tabW.Flush()
+}
+
+// WriteMarkdown writes the stats as markdown tables, listing at most top rows
+// per table if top is positive.
+func WriteMarkdown(w io.Writer, st Stats, top int) error {
+	fmt.Fprintln(w, st.summary(func(s string) string { return "**" + s + "**" }))
+
+	for _, s := range st.sections() {
+		fmt.Fprintf(w, "\n| %s | Count | Share |\n| --- | ---: | ---: |\n", s.title)
+		for _, c := range s.rows(top) {
+			fmt.Fprintf(w, "| %s | %d | %s |\n", strings.ReplaceAll(label(s.title, c.Name), "|", "\\|"), c.Count, percent(c.Count, st.Total))
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the stats as JSON.
+func WriteJSON(w io.Writer, st Stats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(st)
+}
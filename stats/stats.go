@@ -0,0 +1,251 @@
+// Package stats summarizes comments by type, author, directory, extension and age.
+package stats
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/todos"
+)
+
+// Count is the number of comments with a value of a field.
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats summarizes a set of comments.
+type Stats struct {
+	Total int `json:"total"`
+	// Files is the number of files with comments.
+	Files int `json:"files"`
+	// Lines is the number of lines in the searched files and Density the
+	// number of comments per thousand lines. Both are zero if unknown.
+	Lines   int     `json:"lines,omitempty"`
+	Density float64 `json:"density,omitempty"`
+
+	ByType []Count `json:"by_type"`
+	// ByAuthor counts comments without an author under todos.NoAuthor, and
+	// comments with several authors for each, so it can add up to more than
+	// Total.
+	ByAuthor []Count `json:"by_author"`
+	ByDir    []Count `json:"by_dir"`
+	ByExt    []Count `json:"by_ext"`
+	// ByAge counts comments by the age of their line in AgeBuckets order.
+	// It is empty if no comment has a known age.
+	ByAge []Count `json:"by_age,omitempty"`
+}
+
+// AgeBucket is a range of comment ages.
+type AgeBucket struct {
+	Name string
+	// Max is the exclusive upper bound of the bucket, or zero for no bound.
+	Max time.Duration
+}
+
+const day = 24 * time.Hour
+
+// AgeBuckets are the buckets comments are counted in by age. Comments whose
+// age is unknown, such as uncommitted changes, are counted as Uncommitted.
+var AgeBuckets = []AgeBucket{
+	{Name: "< 1 week", Max: 7 * day},
+	{Name: "1 week - 1 month", Max: 30 * day},
+	{Name: "1 - 3 months", Max: 90 * day},
+	{Name: "3 - 6 months", Max: 182 * day},
+	{Name: "6 - 12 months", Max: 365 * day},
+	{Name: "> 1 year"},
+}
+
+// Uncommitted is the age bucket of comments without a known age.
+const Uncommitted = "uncommitted"
+
+// Options configures Compute.
+type Options struct {
+	// Roots are the searched paths. Comments are counted by the first
+	// directory below the root they were found in.
+	Roots []string
+	// Lines is the number of lines in the searched files, used for the density.
+	Lines int
+	// Age returns the time a comment's line was last changed. Ages are not
+	// counted if Age is nil.
+	Age policy.AgeFunc
+	// Now is the time ages are measured against. Defaults to time.Now().
+	Now time.Time
+}
+
+// Compute summarizes the comments.
+func Compute(comments []todos.Comment, opts Options) (Stats, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	st := Stats{Total: len(comments), Lines: opts.Lines}
+	if opts.Lines > 0 {
+		st.Density = float64(len(comments)) * 1000 / float64(opts.Lines)
+	}
+
+	files := map[string]bool{}
+	byType, byAuthor, byDir, byExt := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	byAge := map[string]int{}
+	aged := false
+
+	for _, c := range comments {
+		files[c.File] = true
+		byType[c.Type]++
+		for _, author := range todos.AuthorsOrNone(c) {
+			byAuthor[author]++
+		}
+		byDir[topDir(c.File, opts.Roots)]++
+		byExt[strings.ToLower(filepath.Ext(c.File))]++
+
+		if opts.Age == nil {
+			continue
+		}
+
+		changed, err := opts.Age(c)
+		if err != nil {
+			return Stats{}, err
+		}
+		if changed.IsZero() {
+			byAge[Uncommitted]++
+			continue
+		}
+
+		aged = true
+		byAge[ageBucket(opts.Now.Sub(changed))]++
+	}
+
+	st.Files = len(files)
+	st.ByType = sortedCounts(byType)
+	st.ByAuthor = sortedCounts(byAuthor)
+	st.ByDir = sortedCounts(byDir)
+	st.ByExt = sortedCounts(byExt)
+
+	// Without any committed comment, blame is not available.
+	if aged {
+		for _, bucket := range AgeBuckets {
+			st.ByAge = append(st.ByAge, Count{Name: bucket.Name, Count: byAge[bucket.Name]})
+		}
+		if n := byAge[Uncommitted]; n > 0 {
+			st.ByAge = append(st.ByAge, Count{Name: Uncommitted, Count: n})
+		}
+	}
+
+	return st, nil
+}
+
+// ageBucket returns the name of the bucket of an age.
+func ageBucket(age time.Duration) string {
+	for _, bucket := range AgeBuckets {
+		if bucket.Max == 0 || age < bucket.Max {
+			return bucket.Name
+		}
+	}
+	return AgeBuckets[len(AgeBuckets)-1].Name
+}
+
+// topDir returns the first directory of file below the longest root that
+// contains it, or "." if the file is directly in the root.
+func topDir(file string, roots []string) string {
+	rel := filepath.Clean(file)
+	best := -1
+
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		r, err := filepath.Rel(root, file)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(root) > best {
+			best = len(root)
+			rel = r
+		}
+	}
+
+	dir, _, found := strings.Cut(filepath.ToSlash(rel), "/")
+	if !found || dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// sortedCounts returns the counts ordered from highest to lowest, then by name.
+func sortedCounts(counts map[string]int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, n := range counts {
+		result = append(result, Count{Name: name, Count: n})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count == result[j].Count {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Count > result[j].Count
+	})
+
+	return result
+}
+
+// CountLines returns the number of lines in the files. Files that can't be
+// read and binary files are skipped.
+func CountLines(files []string) (int, error) {
+	total := 0
+	buf := make([]byte, 32*1024)
+
+	for _, path := range files {
+		n, err := countFileLines(path, buf)
+		if err != nil {
+			if os.IsNotExist(err) || os.IsPermission(err) {
+				continue
+			}
+			return 0, err
+		}
+		total += n
+	}
+
+	return total, nil
+}
+
+func countFileLines(path string, buf []byte) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	lines, first := 0, true
+	var last byte
+
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			// A NUL byte at the start of a file marks it as binary.
+			if first && bytes.IndexByte(buf[:n], 0) >= 0 {
+				return 0, nil
+			}
+			first = false
+
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Count a last line without a trailing newline.
+	if !first && last != '\n' {
+		lines++
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	comments := []todos.Comment{
+		{File: "repo/internal/db.go", Line: 1, Type: "FIXME", Author: "alice"},
+		{File: "repo/internal/api/api.go", Line: 2, Type: "TODO"},
+		{File: "repo/main.go", Line: 3, Type: "TODO", Author: "alice"},
+		{File: "repo/Makefile", Line: 4, Type: "TODO"},
+	}
+
+	ages := map[int]time.Time{
+		1: now.Add(-2 * 24 * time.Hour),
+		2: now.Add(-400 * 24 * time.Hour),
+		3: now.Add(-400 * 24 * time.Hour),
+	}
+
+	st, err := Compute(comments, Options{
+		Roots: []string{"repo"},
+		Lines: 2000,
+		Now:   now,
+		Age:   func(c todos.Comment) (time.Time, error) { return ages[c.Line], nil },
+	})
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+
+	want := Stats{
+		Total:    4,
+		Files:    4,
+		Lines:    2000,
+		Density:  2,
+		ByType:   []Count{{"TODO", 3}, {"FIXME", 1}},
+		ByAuthor: []Count{{todos.NoAuthor, 2}, {"alice", 2}},
+		ByDir:    []Count{{".", 2}, {"internal", 2}},
+		ByExt:    []Count{{".go", 3}, {"", 1}},
+		ByAge: []Count{
+			{"< 1 week", 1}, {"1 week - 1 month", 0}, {"1 - 3 months", 0},
+			{"3 - 6 months", 0}, {"6 - 12 months", 0}, {"> 1 year", 2}, {Uncommitted, 1},
+		},
+	}
+	if !cmp.Equal(st, want) {
+		t.Errorf("Compute() \n%s", cmp.Diff(st, want))
+	}
+
+	// Ages are left out when no comment is committed.
+	st, err = Compute(comments, Options{Age: func(todos.Comment) (time.Time, error) { return time.Time{}, nil }})
+	if err != nil || st.ByAge != nil || st.Density != 0 {
+		t.Errorf("Compute() without blame = %+v, %v", st, err)
+	}
+
+	// Every author of a comment is counted.
+	st, err = Compute([]todos.Comment{
+		{File: "a.go", Line: 1, Type: "TODO", Author: "alice", Authors: []string{"alice", "bob"}},
+		{File: "a.go", Line: 2, Type: "TODO", Author: "bob", Authors: []string{"bob"}},
+	}, Options{})
+	if want := []Count{{"bob", 2}, {"alice", 1}}; err != nil || !cmp.Equal(st.ByAuthor, want) {
+		t.Errorf("Compute() with several authors ByAuthor = %v, %v, want %v", st.ByAuthor, err, want)
+	}
+}
+
+func TestTopDir(t *testing.T) {
+	tests := []struct {
+		file  string
+		roots []string
+		want  string
+	}{
+		{file: "main.go", roots: []string{"."}, want: "."},
+		{file: "internal/db/db.go", roots: []string{"."}, want: "internal"},
+		{file: "a/b/c.go", roots: []string{"a", "a/b"}, want: "."},
+		{file: "a/b/c.go", roots: []string{"a"}, want: "b"},
+		{file: "other/x.go", roots: []string{"a"}, want: "other"},
+		{file: "a/b/c.go", roots: []string{"a/b/c.go"}, want: "."},
+	}
+
+	for _, tt := range tests {
+		if got := topDir(tt.file, tt.roots); got != tt.want {
+			t.Errorf("topDir(%q, %q) = %q, want %q", tt.file, tt.roots, got, tt.want)
+		}
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":    "one\ntwo\n",
+		"b.go":    "one\ntwo",
+		"empty":   "",
+		"bin.dat": "\x00\x01\n\n\n",
+	}
+
+	paths := []string{filepath.Join(dir, "missing")}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	if got, err := CountLines(paths); err != nil || got != 4 {
+		t.Errorf("CountLines() = %d, %v, want 4", got, err)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	st := Stats{
+		Total:    5,
+		Files:    2,
+		ByType:   []Count{{"TODO", 3}, {"FIXME", 1}, {"HACK", 1}},
+		ByAuthor: []Count{{"", 5}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, st, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"**5** comments in **2** files\n",
+		"| TODO | 3 | 60% |\n| (2 others) | 2 | 40% |\n",
+		"| (no author) | 5 | 100% |",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteMarkdown() does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/euforic/todos/policy"
+	"github.com/euforic/todos/stats"
+	"github.com/euforic/todos/todos"
+)
+
+var statsCommand = &command{
+	name:    "stats",
+	usage:   "stats [options] [path...]",
+	summary: "Print comment counts by type, author, directory, extension and age",
+	run:     runStats,
+}
+
+// runStats runs the stats command
+func runStats(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	output := fs.String("output", "table", "Output style (table, json, md)")
+	top := fs.Int("top", 10, "Number of rows listed per table, with the rest added up (0 for all)")
+	noAge := fs.Bool("no-age", false, "Do not count comments by age, which runs git blame on every file with comments")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	paths, err := searchPaths(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(fs, flags, configDir(paths))
+	if err != nil {
+		return err
+	}
+
+	comments, err := searchComments(cfg, paths)
+	if err != nil {
+		return err
+	}
+
+	comments, err = filterComments(cfg, comments)
+	if err != nil {
+		return err
+	}
+
+	ignoreList, err := buildIgnoreList(cfg, paths)
+	if err != nil {
+		return err
+	}
+
+	files, err := todos.Files(paths, ignoreList)
+	if err != nil {
+		return err
+	}
+
+	lines, err := stats.CountLines(files)
+	if err != nil {
+		return err
+	}
+
+	opts := stats.Options{Roots: paths, Lines: lines}
+	if !*noAge {
+		opts.Age = policy.BlameAge()
+	}
+
+	st, err := stats.Compute(comments, opts)
+	if err != nil {
+		return err
+	}
+
+	switch *output {
+	case "json":
+		return stats.WriteJSON(os.Stdout, st)
+	case "md":
+		return stats.WriteMarkdown(os.Stdout, st, *top)
+	case "table":
+		return stats.WriteTable(os.Stdout, st, *top)
+	default:
+		return fmt.Errorf("unknown output style %q (table, json, md)", *output)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/euforic/todos/config"
+	"github.com/euforic/todos/pkg/git"
+	"github.com/euforic/todos/tracker"
+)
+
+var syncCommand = &command{
+	name:    "sync",
+	usage:   "sync [options] [path...]",
+	summary: "Open an issue for every comment without one, write its number into the comment and close issues of removed comments",
+	run:     runSync,
+}
+
+// runSync runs the sync command
+func runSync(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	dryRun := fs.Bool("dry-run", false, "Print what would be done without changing the tracker or any files")
+	noClose := fs.Bool("no-close", false, "Do not close issues whose comments are gone. Issues are never closed when filter flags or -files-from narrow the scan")
+	output := fs.String("output", "text", "Output style (text, json)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output style %q (text, json)", *output)
+	}
+
+	cfg, comments, err := findComments(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	t, err := tracker.New(cfg.Tracker)
+	if err != nil {
+		return err
+	}
+
+	// Issue titles in a file tracker look like comments.
+	if cfg.Tracker.Path != "" {
+		kept := comments[:0]
+		for _, comment := range comments {
+			if filepath.Clean(comment.File) != filepath.Clean(cfg.Tracker.Path) {
+				kept = append(kept, comment)
+			}
+		}
+		comments = kept
+	}
+
+	// Only issues of comments in the searched paths can be closed. Filter
+	// flags and file lists leave out comments whose issues can't be told
+	// apart, so nothing is closed then. The filter of the config file is
+	// applied on every run, so comments it leaves out have no issues.
+	closeIssues := !*noClose
+	if closeIssues && (andFilters(flags.filter, flagFilter(flags)) != "" || flags.filesFrom != "") {
+		closeIssues = false
+		fmt.Fprintln(os.Stderr, "Not closing issues: filter flags or -files-from narrow the scan")
+	}
+
+	root, err := syncRoot(flags)
+	if err != nil {
+		return err
+	}
+
+	actions, syncErr := tracker.Sync(t, comments, tracker.SyncOptions{
+		Label:  cfg.Tracker.Label,
+		Close:  closeIssues,
+		Paths:  fs.Args(),
+		Root:   root,
+		DryRun: *dryRun,
+	})
+
+	// Report what was done even if sync stopped part way.
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(actions); err != nil {
+			return err
+		}
+	} else {
+		writeActions(actions, *dryRun)
+	}
+
+	return syncErr
+}
+
+// syncRoot returns the directory the paths recorded in issues are relative
+// to: the root of the git repository, or else the directory of the config
+// file, or else the working directory.
+func syncRoot(flags *searchFlags) (string, error) {
+	if root, err := git.Root("."); err == nil {
+		return root, nil
+	}
+
+	path := flags.configFile
+	if path == "" && !flags.noConfig {
+		found, err := config.Find(".")
+		if err != nil {
+			return "", err
+		}
+		path = found
+	}
+	if path == "" {
+		return ".", nil
+	}
+	return filepath.Dir(path), nil
+}
+
+// writeActions prints a line for each sync action
+func writeActions(actions []tracker.Action, dryRun bool) {
+	tabW := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	for _, action := range actions {
+		verb := map[string]string{
+			tracker.ActionCreate: "created",
+			tracker.ActionUpdate: "updated",
+			tracker.ActionLink:   "linked",
+			tracker.ActionClose:  "closed",
+		}[action.Kind]
+		if dryRun {
+			verb = "would " + action.Kind
+		}
+
+		location := ""
+		if action.Comment != nil {
+			location = fmt.Sprintf("%s:%d", action.Comment.File, action.Comment.Line)
+		}
+
+		fmt.Fprintf(tabW, "%s\t%s\t%s\t%s\n", verb, action.Issue.ID, location, action.Issue.Title)
+	}
+
+	tabW.Flush()
+}
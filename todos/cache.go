@@ -0,0 +1,257 @@
+package todos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheVersion identifies the parser that produced cached comments. It must
+// be increased whenever a change to Parse or the language table changes the
+// comments found in a file, so caches written by older versions are discarded.
+const CacheVersion = 4
+
+// Cache stores the comments parsed from each file on disk so that later
+// searches only parse files that changed. A file is unchanged if its size and
+// modification time match the cached entry or, failing that, if its content
+// hash does, which keeps the cache useful after a fresh checkout resets
+// modification times.
+//
+// The cache is only valid for the matcher options it was opened with.
+// Entries written with other settings or by another CacheVersion are
+// discarded when the cache is opened.
+type Cache struct {
+	path    string
+	key     string
+	matcher *Matcher
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	used    map[string]bool
+	hits    int
+	misses  int
+}
+
+// cacheFile is the on-disk format of a Cache.
+type cacheFile struct {
+	Version int                   `json:"version"`
+	Key     string                `json:"key"`
+	Files   map[string]cacheEntry `json:"files"`
+}
+
+type cacheEntry struct {
+	Size     int64     `json:"size"`
+	ModTime  int64     `json:"mod_time"`
+	Hash     string    `json:"hash"`
+	Comments []Comment `json:"comments"`
+}
+
+// OpenCache loads the cache stored at path for the given comment types and
+// parse mode. A missing, unreadable or outdated cache file results in an
+// empty cache.
+func OpenCache(path string, commentTypes []string, permissive bool) (*Cache, error) {
+	m, err := NewMatcher(MatchOptions{Types: commentTypes, Permissive: permissive})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.OpenCache(path)
+}
+
+// OpenCache loads the cache stored at path for comments found by the
+// matcher, like the OpenCache function.
+func (m *Matcher) OpenCache(path string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		key:     cacheKey(m),
+		matcher: m,
+		entries: map[string]cacheEntry{},
+		used:    map[string]bool{},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return c, nil
+	}
+	if file.Version == CacheVersion && file.Key == c.key && file.Files != nil {
+		c.entries = file.Files
+	}
+
+	return c, nil
+}
+
+// NewCache returns an empty cache for comments found by the matcher that is
+// only kept in memory, for processes that search the same files repeatedly.
+// Save does nothing for it.
+func (m *Matcher) NewCache() *Cache {
+	return &Cache{
+		key:     cacheKey(m),
+		matcher: m,
+		entries: map[string]cacheEntry{},
+		used:    map[string]bool{},
+	}
+}
+
+// cacheKey identifies the settings that affect the comments parsed from a file.
+func cacheKey(m *Matcher) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s", CacheVersion, m.Key())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SearchPaths searches paths like the SearchPaths function, using the cached
+// comments of unchanged files. The cache file itself is never searched.
+func (c *Cache) SearchPaths(paths []string, ignores []string) ([]Comment, error) {
+	files, err := Files(paths, ignores)
+	if err != nil {
+		return nil, err
+	}
+
+	self := ""
+	if c.path != "" {
+		self, _ = filepath.Abs(c.path)
+	}
+
+	results := make([][]Comment, len(files))
+	var wg sync.WaitGroup
+
+	for i, path := range files {
+		if abs, err := filepath.Abs(path); err == nil && abs == self {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			results[i] = c.comments(path)
+		}(i, path)
+	}
+
+	wg.Wait()
+
+	comments := []Comment{}
+	for _, fileComments := range results {
+		comments = append(comments, fileComments...)
+	}
+
+	return comments, nil
+}
+
+// comments returns the comments of the file at path, parsing it only if it
+// changed since it was cached. Files that can't be read have no comments.
+func (c *Cache) comments(path string) []Comment {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+
+	if ok && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() {
+		c.store(path, entry, true)
+		return entry.Comments
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	hit := ok && entry.Hash == hash
+	if !hit {
+		comments, err := c.matcher.Parse(bytes.NewReader(data), path)
+		if err != nil {
+			return nil
+		}
+		entry = cacheEntry{Hash: hash, Comments: comments}
+	}
+
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime().UnixNano()
+	c.store(path, entry, hit)
+
+	return entry.Comments
+}
+
+func (c *Cache) store(path string, entry cacheEntry, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[path] = entry
+	c.used[path] = true
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+// Stats returns the number of files whose comments were taken from the cache
+// and the number of files that were parsed.
+func (c *Cache) Stats() (hits int, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits, c.misses
+}
+
+// Save writes the cache to its file. Entries of files that no longer exist
+// are dropped.
+func (c *Cache) Save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path := range c.entries {
+		if c.used[path] {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.entries, path)
+		}
+	}
+
+	data, err := json.Marshal(cacheFile{Version: CacheVersion, Key: c.key, Files: c.entries})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted run never leaves a
+	// truncated cache behind.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
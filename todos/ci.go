@@ -0,0 +1,217 @@
+package todos
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// sarifSchema is the JSON schema of the SARIF documents written by WriteSARIF
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine int `json:"startLine"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// sarifLevels maps severities to SARIF result levels
+var sarifLevels = map[string]string{
+	SeverityInfo:    "note",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// WriteSARIF writes the comments to the io.Writer as a SARIF 2.1.0 log, with
+// a rule for each comment type. A log without results is written when there
+// are no comments, so code scanning uploads always have a file.
+func WriteSARIF(w io.Writer, comments []Comment, sortBy []SortKey) error {
+	SortComments(comments, sortBy)
+
+	driver := sarifDriver{Name: "todos", InformationURI: "https://github.com/euforic/todos", Rules: []sarifRule{}}
+	ruleIndex := map[string]int{}
+	results := []sarifResult{}
+
+	for _, comment := range comments {
+		level := sarifLevels[SeverityOf(comment)]
+
+		index, ok := ruleIndex[comment.Type]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[comment.Type] = index
+
+			rule := sarifRule{ID: comment.Type, ShortDescription: sarifMessage{Text: comment.Type + " comment"}}
+			rule.DefaultConfiguration.Level = level
+			driver.Rules = append(driver.Rules, rule)
+		}
+
+		result := sarifResult{
+			RuleID:    comment.Type,
+			RuleIndex: index,
+			Level:     level,
+			Message:   sarifMessage{Text: Summary(comment)},
+		}
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = slashPath(comment.File)
+		location.PhysicalLocation.Region.StartLine = comment.Line
+		result.Locations = []sarifLocation{location}
+
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// WriteCheckstyle writes the comments to the io.Writer as a checkstyle XML
+// report, with an entry for each file. The source of each comment is
+// todos.TYPE.
+func WriteCheckstyle(w io.Writer, comments []Comment, sortBy []SortKey) error {
+	SortComments(comments, sortBy)
+
+	report := checkstyleReport{Version: "4.3"}
+	files := map[string]int{}
+
+	for _, comment := range comments {
+		i, ok := files[comment.File]
+		if !ok {
+			i = len(report.Files)
+			files[comment.File] = i
+			report.Files = append(report.Files, checkstyleFile{Name: comment.File})
+		}
+
+		report.Files[i].Errors = append(report.Files[i].Errors, checkstyleError{
+			Line:     comment.Line,
+			Severity: SeverityOf(comment),
+			Message:  Summary(comment),
+			Source:   "todos." + comment.Type,
+		})
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// githubCommands maps severities to GitHub Actions workflow commands
+var githubCommands = map[string]string{
+	SeverityInfo:    "notice",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+// WriteGitHub writes the comments to the io.Writer as GitHub Actions workflow
+// commands, which annotate the lines of the comments in pull requests
+func WriteGitHub(w io.Writer, comments []Comment, sortBy []SortKey) error {
+	SortComments(comments, sortBy)
+
+	for _, comment := range comments {
+		_, err := fmt.Fprintf(w, "::%s file=%s,line=%d,title=%s::%s\n",
+			githubCommands[SeverityOf(comment)],
+			escapeGitHubProperty(slashPath(comment.File)),
+			comment.Line,
+			escapeGitHubProperty(comment.Type),
+			escapeGitHubData(Summary(comment)),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// escapeGitHubData escapes the message of a workflow command
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// slashPath returns the path of a file as CI tools expect it, with forward
+// slashes and no leading ./
+func slashPath(path string) string {
+	return strings.TrimPrefix(filepath.ToSlash(path), "./")
+}
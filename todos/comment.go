@@ -6,76 +6,74 @@ import (
 	"html/template"
 	"io"
 	"os"
-	"sort"
 	"strings"
 	"text/tabwriter"
 )
 
-// WriteFileGroup writes the comments to the io.Writer as a file list
-func WriteFileGroup(w io.Writer, comments []Comment, sortby string, desc bool) error {
+// WriteFileGroup writes the comments to the io.Writer as lists grouped by
+// the groupBy fields, or by file if groupBy is empty
+func WriteFileGroup(w io.Writer, comments []Comment, sortBy []SortKey, groupBy []string) error {
 	if w == nil {
 		return fmt.Errorf("output is nil")
 	}
 
+	if len(groupBy) == 0 {
+		groupBy = []string{"file"}
+	}
+
+	byFile := false
+	for _, field := range groupBy {
+		byFile = byFile || field == "file"
+	}
+
+	SortComments(comments, sortBy)
+
 	tabW := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeGroups(tabW, GroupComments(comments, groupBy, sortBy), 0, func(comment Comment) string {
+		location := fmt.Sprintf("%s:%d", comment.File, comment.Line)
+		if byFile {
+			location = fmt.Sprintf("%d", comment.Line)
+		}
 
-	fileGroups := make(map[string][]Comment)
-	for _, comment := range comments {
-		fileGroups[comment.File] = append(fileGroups[comment.File], comment)
-	}
-	keys := make([]string, 0, len(fileGroups))
-	sort.Strings(keys)
-
-	for file, comments := range fileGroups {
-		fmt.Fprintf(tabW, "%s [%d Comments]:\n", file, len(comments))
-		if sortby != "" {
-			sort.Slice(comments, func(i, j int) bool {
-				switch sortby {
-				case "author":
-					return comments[i].Author < comments[j].Author
-				case "file":
-					return comments[i].File < comments[j].File
-				case "line":
-					return comments[i].Line < comments[j].Line
-				case "type":
-					return comments[i].Type < comments[j].Type
-				case "text":
-					return comments[i].Text < comments[j].Text
-				default:
-					return comments[i].File < comments[j].File
-				}
-			})
-
-			if desc {
-				for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
-					comments[i], comments[j] = comments[j], comments[i]
-				}
-			}
+		author := ""
+		if comment.Author != "" {
+			author = "(" + AuthorList(comment) + ")"
 		}
 
-		for i, comment := range comments {
-			author := ""
-			if comment.Author != "" {
-				author = "(" + comment.Author + ")"
-			}
+		return fmt.Sprintf("%s\t|\t%s%s:\t%s\t", location, comment.Type, author, comment.Text)
+	})
+
+	return tabW.Flush()
+}
 
-			fmt.Fprintf(tabW, "%d\t|\t%s%s:\t%s\t\n", comment.Line, comment.Type, author, comment.Text)
-			if i == len(comments)-1 {
-				fmt.Fprintf(tabW, "\n")
-			}
+// writeGroups writes a header with the comment count for each group,
+// followed by its subgroups indented or its comments formatted by row
+func writeGroups(w io.Writer, groups []Group, depth int, row func(Comment) string) {
+	indent := strings.Repeat("  ", depth)
+
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s%s [%d Comments]:\n", indent, group.Label(), group.Count)
+
+		if len(group.Groups) > 0 {
+			writeGroups(w, group.Groups, depth+1, row)
+		}
+		for _, comment := range group.Comments {
+			fmt.Fprintf(w, "%s%s\n", indent, row(comment))
 		}
-	}
 
-	return nil
+		if depth == 0 {
+			fmt.Fprintln(w)
+		}
+	}
 }
 
 // WriteJSON writes the comments to the io.Writer as JSON
-func WriteJSON(w io.Writer, comments []Comment, sortby string, desc bool) error {
+func WriteJSON(w io.Writer, comments []Comment, sortBy []SortKey) error {
 	if len(comments) == 0 {
 		return nil
 	}
 
-	sortComments(comments, sortby, desc)
+	SortComments(comments, sortBy)
 
 	commentsJSON, err := json.MarshalIndent(comments, "", "  ")
 	if err != nil {
@@ -90,51 +88,129 @@ func WriteJSON(w io.Writer, comments []Comment, sortby string, desc bool) error
 	return nil
 }
 
-// WriteTable writes the comments to the io.Writer as a table
-func WriteTable(w io.Writer, comments []Comment, sortby string, desc bool) error {
+// WriteTable writes the comments to the io.Writer as a table, with a
+// section for each group if groupBy is not empty
+func WriteTable(w io.Writer, comments []Comment, sortBy []SortKey, groupBy []string) error {
+	return writeTable(w, comments, sortBy, groupBy, false)
+}
+
+// WriteColorTable writes the comments to the io.Writer as a table like
+// WriteTable, with the type of each comment colored by its severity for
+// terminals
+func WriteColorTable(w io.Writer, comments []Comment, sortBy []SortKey, groupBy []string) error {
+	return writeTable(w, comments, sortBy, groupBy, true)
+}
+
+// severityColors are the terminal colors of the severities. All the codes,
+// including the header's bold, have the same length so the tabwriter still
+// aligns the colored column.
+var severityColors = map[string]string{
+	SeverityInfo:    "\033[36m",
+	SeverityWarning: "\033[33m",
+	SeverityError:   "\033[31m",
+}
+
+const (
+	colorHeader = "\033[01m"
+	colorReset  = "\033[0m"
+)
+
+func writeTable(w io.Writer, comments []Comment, sortBy []SortKey, groupBy []string, color bool) error {
 	if len(comments) == 0 {
 		return nil
 	}
 
-	sortComments(comments, sortby, desc)
+	SortComments(comments, sortBy)
 
 	tabW := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
-	header := fmt.Sprintf("%s\t%s\t%s\t%s", "Type", "Author", "File:Line", "Text")
+	row := func(comment Comment) string {
+		typ := comment.Type
+		if color {
+			typ = severityColors[SeverityOf(comment)] + typ + colorReset
+		}
+		return fmt.Sprintf("%s\t%s\t%s:%d\t%s", typ, AuthorList(comment), comment.File, comment.Line, comment.Text)
+	}
+
+	if len(groupBy) > 0 {
+		writeGroups(tabW, GroupComments(comments, groupBy, sortBy), 0, func(comment Comment) string {
+			return "  " + row(comment)
+		})
+		return tabW.Flush()
+	}
+
+	typ := "Type"
+	if color {
+		typ = colorHeader + typ + colorReset
+	}
+
+	header := fmt.Sprintf("%s\t%s\t%s\t%s", typ, "Author", "File:Line", "Text")
 	fmt.Fprintln(tabW, header)
 
 	for _, comment := range comments {
-		commentString := fmt.Sprintf("%s\t%s\t%s:%d\t%s", comment.Author, comment.Type, comment.File, comment.Line, comment.Text)
-		fmt.Fprintln(tabW, commentString)
+		fmt.Fprintln(tabW, row(comment))
 	}
 
 	return tabW.Flush()
 }
 
-// WriteMarkdown writes the comments to the io.Writer as a markdown table
-func WriteMarkdown(w io.Writer, comments []Comment, sortby string, desc bool) error {
+// WriteMarkdown writes the comments to the io.Writer as a markdown table, with
+// a heading and table for each group if groupBy is not empty
+func WriteMarkdown(w io.Writer, comments []Comment, sortBy []SortKey, groupBy []string) error {
 	if len(comments) == 0 {
 		return nil
 	}
 
-	sortComments(comments, sortby, desc)
+	SortComments(comments, sortBy)
+
+	if len(groupBy) == 0 {
+		writeMarkdownTable(w, comments)
+		return nil
+	}
+
+	writeMarkdownGroups(w, GroupComments(comments, groupBy, sortBy), 0)
+	return nil
+}
 
-	fmt.Println("| Type | Author | File:Line | Text |")
-	fmt.Println("| --- | --- | --- | --- |")
+func writeMarkdownGroups(w io.Writer, groups []Group, depth int) {
+	level := depth + 2
+	if level > 6 {
+		level = 6
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s %s (%d)\n\n", strings.Repeat("#", level), EscapeMarkdown(group.Label()), group.Count)
+
+		if len(group.Groups) > 0 {
+			writeMarkdownGroups(w, group.Groups, depth+1)
+			continue
+		}
+
+		writeMarkdownTable(w, group.Comments)
+		fmt.Fprintln(w)
+	}
+}
+
+func writeMarkdownTable(w io.Writer, comments []Comment) {
+	fmt.Fprintln(w, "| Type | Author | File:Line | Text |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
 
 	for _, comment := range comments {
-		fmt.Printf("| %s | %s | %s | %s:%d |\n", comment.Type, comment.Author, comment.Text, comment.File, comment.Line)
+		fmt.Fprintf(w, "| %s | %s | %s:%d | %s |\n", comment.Type, EscapeMarkdown(AuthorList(comment)), EscapeMarkdown(comment.File), comment.Line, EscapeMarkdown(comment.Text))
 	}
+}
 
-	return nil
+// EscapeMarkdown escapes the pipes that would end a markdown table cell
+func EscapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
 }
 
 // WriteTemplate writes the comments to the io.Writer using the given template
-func WriteTemplate(w io.Writer, comments []Comment, sortby string, desc bool, sourceStr string) error {
+func WriteTemplate(w io.Writer, comments []Comment, sortBy []SortKey, sourceStr string) error {
 	if len(comments) == 0 {
 		return nil
 	}
 
-	sortComments(comments, sortby, desc)
+	SortComments(comments, sortBy)
 
 	sourceStr = strings.Replace(sourceStr, `\n`, "\n", -1)
 	sourceStr = strings.Replace(sourceStr, `\t`, "\t", -1)
@@ -147,29 +223,17 @@ func WriteTemplate(w io.Writer, comments []Comment, sortby string, desc bool, so
 	return t.Execute(w, comments)
 }
 
-// sortComments sorts the comments by the given sortby string and
-// reverses the order if the sortby string ends with ":desc"
-func sortComments(comments []Comment, sortby string, desc bool) {
-	sort.Slice(comments, func(i, j int) bool {
-		switch sortby {
-		case "author":
-			return comments[i].Author < comments[j].Author
-		case "file":
-			return comments[i].File < comments[j].File
-		case "line":
-			return comments[i].Line < comments[j].Line
-		case "type":
-			return comments[i].Type < comments[j].Type
-		case "text":
-			return comments[i].Text < comments[j].Text
-		default:
-			return comments[i].File < comments[j].File
-		}
-	})
-
-	if desc {
-		for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
-			comments[i], comments[j] = comments[j], comments[i]
-		}
+// Summary returns a comment in the strict format with all of its authors,
+// such as "TODO(alice, bob): text", as used in messages about a comment.
+func Summary(comment Comment) string {
+	s := comment.Type
+	if authors := AuthorList(comment); authors != "" {
+		s += "(" + authors + ")"
 	}
+	return s + ": " + comment.Text
+}
+
+// AuthorList returns the authors of a comment separated by commas
+func AuthorList(comment Comment) string {
+	return strings.Join(Authors(comment), ", ")
 }
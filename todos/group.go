@@ -0,0 +1,190 @@
+package todos
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GroupFields are the fields comments can be grouped by. The dir group is
+// the directory of the file, tag groups comments by the #hashtags in their
+// text, owner by their code owners and severity by the severity of their
+// type.
+var GroupFields = []string{"file", "author", "type", "dir", "tag", "owner", "severity"}
+
+// tagRegex matches #hashtags. Tags must start with a letter, so issue
+// references such as #123 are not tags.
+var tagRegex = regexp.MustCompile(`(?:^|[^\w#/&])#([A-Za-z][\w-]*)`)
+
+// Group is a group of comments that share the value of a field.
+type Group struct {
+	Field string
+	Name  string
+	// Count is the number of comments in the group, including subgroups.
+	Count int
+	// Groups are the subgroups when grouping by more than one field.
+	// Otherwise Comments are the comments in the group.
+	Groups   []Group
+	Comments []Comment
+}
+
+// Label returns the name of the group, or a placeholder if it is empty.
+func (g Group) Label() string {
+	if g.Name == "" {
+		return "(no " + g.Field + ")"
+	}
+	return g.Name
+}
+
+// ParseGroupBy parses a comma-separated list of fields to group by, outermost
+// first, such as "author,type".
+func ParseGroupBy(spec string) ([]string, error) {
+	fields := []string{}
+
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+
+		if !validGroupField(field) {
+			return nil, fmt.Errorf("unknown group field %q (%s)", field, strings.Join(GroupFields, ", "))
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func validGroupField(field string) bool {
+	for _, f := range GroupFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupComments groups the comments by the fields, nesting a level of groups
+// for each field after the first. Comments keep their order within a group.
+// Groups are ordered by name, or by level for severities, descending if
+// sortBy sorts the field in descending order, with the group of comments
+// without a value last. A comment with several authors, tags or owners is in
+// the group of each.
+func GroupComments(comments []Comment, fields []string, sortBy []SortKey) []Group {
+	if len(fields) == 0 {
+		return nil
+	}
+	field := fields[0]
+
+	groups := []Group{}
+	index := map[string]int{}
+
+	for _, c := range comments {
+		for _, name := range groupNames(c, field) {
+			i, ok := index[name]
+			if !ok {
+				i = len(groups)
+				index[name] = i
+				groups = append(groups, Group{Field: field, Name: name})
+			}
+			groups[i].Comments = append(groups[i].Comments, c)
+		}
+	}
+
+	desc := false
+	for _, key := range sortBy {
+		if key.Field == field {
+			desc = key.Desc
+			break
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].Name, groups[j].Name
+		if (a == "") != (b == "") {
+			return b == ""
+		}
+		if desc {
+			return compareGroupNames(field, a, b) > 0
+		}
+		return compareGroupNames(field, a, b) < 0
+	})
+
+	for i := range groups {
+		groups[i].Count = len(groups[i].Comments)
+		if len(fields) > 1 {
+			groups[i].Groups = GroupComments(groups[i].Comments, fields[1:], sortBy)
+			groups[i].Comments = nil
+		}
+	}
+
+	return groups
+}
+
+// compareGroupNames compares the names of two groups of a field, returning
+// -1, 0 or 1. Severities are ordered from info to error, other names
+// naturally.
+func compareGroupNames(field, a, b string) int {
+	if field == "severity" {
+		x, y := SeverityRank(a), SeverityRank(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return compareNatural(a, b)
+}
+
+// groupNames returns the names of the groups of a comment.
+func groupNames(c Comment, field string) []string {
+	switch field {
+	case "author":
+		authors := Authors(c)
+		if len(authors) == 0 {
+			return []string{""}
+		}
+		return authors
+	case "type":
+		return []string{c.Type}
+	case "dir":
+		return []string{filepath.ToSlash(filepath.Dir(c.File))}
+	case "tag":
+		tags := Tags(c.Text)
+		if len(tags) == 0 {
+			return []string{""}
+		}
+		return tags
+	case "owner":
+		if len(c.Owners) == 0 {
+			return []string{""}
+		}
+		return c.Owners
+	case "severity":
+		return []string{SeverityOf(c)}
+	default:
+		return []string{c.File}
+	}
+}
+
+// Tags returns the #hashtags in text without the #, in order of appearance
+// and without duplicates.
+func Tags(text string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, m := range tagRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			tags = append(tags, m[1])
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,190 @@
+package todos
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Language describes the comment syntax of a programming language.
+type Language struct {
+	Name          string
+	LineComments  []string
+	BlockComments [][2]string
+}
+
+var (
+	cStyle    = Language{LineComments: []string{"//"}, BlockComments: [][2]string{{"/*", "*/"}}}
+	hashStyle = Language{LineComments: []string{"#"}}
+	htmlStyle = Language{BlockComments: [][2]string{{"<!--", "-->"}}}
+)
+
+// named returns a copy of l with the given name.
+func (l Language) named(name string) *Language {
+	l.Name = name
+	return &l
+}
+
+// languagesByExt maps lower case file extensions to languages.
+var languagesByExt = map[string]*Language{
+	".go":     cStyle.named("Go"),
+	".c":      cStyle.named("C"),
+	".h":      cStyle.named("C"),
+	".cc":     cStyle.named("C++"),
+	".cpp":    cStyle.named("C++"),
+	".cxx":    cStyle.named("C++"),
+	".hpp":    cStyle.named("C++"),
+	".cs":     cStyle.named("C#"),
+	".java":   cStyle.named("Java"),
+	".kt":     cStyle.named("Kotlin"),
+	".kts":    cStyle.named("Kotlin"),
+	".scala":  cStyle.named("Scala"),
+	".swift":  cStyle.named("Swift"),
+	".dart":   cStyle.named("Dart"),
+	".rs":     cStyle.named("Rust"),
+	".zig":    {Name: "Zig", LineComments: []string{"//"}},
+	".js":     cStyle.named("JavaScript"),
+	".jsx":    cStyle.named("JavaScript"),
+	".mjs":    cStyle.named("JavaScript"),
+	".cjs":    cStyle.named("JavaScript"),
+	".ts":     cStyle.named("TypeScript"),
+	".tsx":    cStyle.named("TypeScript"),
+	".proto":  cStyle.named("Protocol Buffers"),
+	".css":    {Name: "CSS", BlockComments: [][2]string{{"/*", "*/"}}},
+	".scss":   cStyle.named("SCSS"),
+	".less":   cStyle.named("Less"),
+	".php":    {Name: "PHP", LineComments: []string{"//", "#"}, BlockComments: [][2]string{{"/*", "*/"}}},
+	".py":     {Name: "Python", LineComments: []string{"#"}, BlockComments: [][2]string{{`"""`, `"""`}, {"'''", "'''"}}},
+	".rb":     {Name: "Ruby", LineComments: []string{"#"}, BlockComments: [][2]string{{"=begin", "=end"}}},
+	".pl":     hashStyle.named("Perl"),
+	".pm":     hashStyle.named("Perl"),
+	".r":      hashStyle.named("R"),
+	".ex":     hashStyle.named("Elixir"),
+	".exs":    hashStyle.named("Elixir"),
+	".sh":     hashStyle.named("Shell"),
+	".bash":   hashStyle.named("Shell"),
+	".zsh":    hashStyle.named("Shell"),
+	".fish":   hashStyle.named("Shell"),
+	".ps1":    {Name: "PowerShell", LineComments: []string{"#"}, BlockComments: [][2]string{{"<#", "#>"}}},
+	".yml":    hashStyle.named("YAML"),
+	".yaml":   hashStyle.named("YAML"),
+	".toml":   hashStyle.named("TOML"),
+	".ini":    {Name: "INI", LineComments: []string{";", "#"}},
+	".tf":     {Name: "Terraform", LineComments: []string{"#", "//"}, BlockComments: [][2]string{{"/*", "*/"}}},
+	".hcl":    {Name: "HCL", LineComments: []string{"#", "//"}, BlockComments: [][2]string{{"/*", "*/"}}},
+	".nim":    {Name: "Nim", LineComments: []string{"#"}, BlockComments: [][2]string{{"#[", "]#"}}},
+	".sql":    {Name: "SQL", LineComments: []string{"--"}, BlockComments: [][2]string{{"/*", "*/"}}},
+	".lua":    {Name: "Lua", LineComments: []string{"--"}, BlockComments: [][2]string{{"--[[", "]]"}}},
+	".hs":     {Name: "Haskell", LineComments: []string{"--"}, BlockComments: [][2]string{{"{-", "-}"}}},
+	".elm":    {Name: "Elm", LineComments: []string{"--"}, BlockComments: [][2]string{{"{-", "-}"}}},
+	".erl":    {Name: "Erlang", LineComments: []string{"%"}},
+	".tex":    {Name: "TeX", LineComments: []string{"%"}},
+	".clj":    {Name: "Clojure", LineComments: []string{";"}},
+	".lisp":   {Name: "Lisp", LineComments: []string{";"}, BlockComments: [][2]string{{"#|", "|#"}}},
+	".el":     {Name: "Emacs Lisp", LineComments: []string{";"}},
+	".vim":    {Name: "Vim Script", LineComments: []string{`"`}},
+	".html":   htmlStyle.named("HTML"),
+	".htm":    htmlStyle.named("HTML"),
+	".xml":    htmlStyle.named("XML"),
+	".svg":    htmlStyle.named("XML"),
+	".md":     htmlStyle.named("Markdown"),
+	".vue":    {Name: "Vue", LineComments: []string{"//"}, BlockComments: [][2]string{{"<!--", "-->"}, {"/*", "*/"}}},
+	".svelte": {Name: "Svelte", LineComments: []string{"//"}, BlockComments: [][2]string{{"<!--", "-->"}, {"/*", "*/"}}},
+}
+
+// languagesByName maps lower case file names without a useful extension to languages.
+var languagesByName = map[string]*Language{
+	"makefile":       hashStyle.named("Makefile"),
+	"gnumakefile":    hashStyle.named("Makefile"),
+	"dockerfile":     hashStyle.named("Dockerfile"),
+	"containerfile":  hashStyle.named("Dockerfile"),
+	"cmakelists.txt": hashStyle.named("CMake"),
+	"gemfile":        hashStyle.named("Ruby"),
+	"rakefile":       hashStyle.named("Ruby"),
+	"jenkinsfile":    cStyle.named("Groovy"),
+	".bashrc":        hashStyle.named("Shell"),
+	".zshrc":         hashStyle.named("Shell"),
+	".gitignore":     hashStyle.named("Ignore List"),
+	".dockerignore":  hashStyle.named("Ignore List"),
+	".editorconfig":  {Name: "INI", LineComments: []string{";", "#"}},
+}
+
+// DetectLanguage returns the language of the file at path based on its name,
+// or nil if the language is not known. The file does not need to exist, so
+// content read from elsewhere can be given a virtual file name.
+func DetectLanguage(path string) *Language {
+	name := strings.ToLower(filepath.Base(path))
+
+	if lang, ok := languagesByName[name]; ok {
+		return lang
+	}
+
+	if lang, ok := languagesByExt[filepath.Ext(name)]; ok {
+		return lang
+	}
+
+	// Files such as Dockerfile.dev and Makefile.inc.
+	if base, _, ok := strings.Cut(name, "."); ok {
+		if lang, ok := languagesByName[base]; ok {
+			return lang
+		}
+	}
+
+	return nil
+}
+
+// segment is a part of a line and its byte offset in the line.
+type segment struct {
+	text   string
+	offset int
+}
+
+// commentSegments returns the parts of a line that are inside comments.
+// block is the index of the block comment open at the start of the line, or
+// -1, and is updated to the one open at its end. Comment delimiters inside
+// strings are not recognized.
+func (l *Language) commentSegments(line string, block *int) []segment {
+	segments := []segment{}
+	offset := 0
+
+	for line != "" {
+		if *block >= 0 {
+			end := l.BlockComments[*block][1]
+			i := strings.Index(line, end)
+			if i < 0 {
+				return append(segments, segment{line, offset})
+			}
+			segments = append(segments, segment{line[:i], offset})
+			line = line[i+len(end):]
+			offset += i + len(end)
+			*block = -1
+			continue
+		}
+
+		// Find the first delimiter, preferring the longer one at the same
+		// position, such as --[[ over -- in Lua.
+		at, token, opens := -1, "", -1
+		for _, start := range l.LineComments {
+			if i := strings.Index(line, start); i >= 0 && (at < 0 || i < at || i == at && len(start) > len(token)) {
+				at, token, opens = i, start, -1
+			}
+		}
+		for n, pair := range l.BlockComments {
+			if i := strings.Index(line, pair[0]); i >= 0 && (at < 0 || i < at || i == at && len(pair[0]) > len(token)) {
+				at, token, opens = i, pair[0], n
+			}
+		}
+
+		if at < 0 {
+			break
+		}
+		if opens < 0 {
+			return append(segments, segment{line[at+len(token):], offset + at + len(token)})
+		}
+
+		*block = opens
+		line = line[at+len(token):]
+		offset += at + len(token)
+	}
+
+	return segments
+}
@@ -0,0 +1,399 @@
+package todos
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var errPattern = errors.New("invalid pattern")
+
+// patternGroups are the named groups a Pattern may use.
+var patternGroups = map[string]bool{"type": true, "author": true, "text": true, "issue": true}
+
+// Pattern is a user-defined regular expression that finds comments written
+// in a format the comment types don't cover, such as @todo, TODO-123: or
+// Rust's todo!() macro. The named groups type, author, text and issue set
+// those fields of the comments found.
+type Pattern struct {
+	Name  string `json:"name,omitempty"`
+	Regex string `json:"regex"`
+	// Type is the type of the comments found by a regex without a type
+	// group.
+	Type string `json:"type,omitempty"`
+}
+
+// MatchOptions configures a Matcher.
+type MatchOptions struct {
+	// Types are the comment types to search for, matched literally.
+	Types []string
+	// CaseSensitive matches Types as written instead of in any case, so
+	// TODO doesn't match "todo list". Types are reported as written rather
+	// than in upper case.
+	CaseSensitive bool
+	// TypeAliases maps canonical types to variants that are searched for
+	// along with them and reported as them, such as FIXME to FIX-ME and
+	// BUG. Only the variants of types in Types are searched for.
+	TypeAliases map[string][]string
+	// Permissive enables the looser comment format for Types.
+	Permissive bool
+	// Patterns are tried on each line before Types, in order.
+	Patterns []Pattern
+	// MinTextLength is the minimum number of characters in the text of a
+	// comment. Shorter comments are skipped.
+	MinTextLength int
+	// RequireComment only matches Types inside comments of the file's
+	// language, such as after // in Go or between <!-- and --> in HTML.
+	// Files of unknown languages are matched as if it was false. Patterns
+	// are always matched against the whole line.
+	RequireComment bool
+}
+
+// compiledPattern is a Pattern with its regex compiled.
+type compiledPattern struct {
+	Pattern
+	re *regexp.Regexp
+}
+
+// Matcher finds comments in the lines of a file.
+type Matcher struct {
+	opts     MatchOptions
+	patterns []compiledPattern
+	types    *regexp.Regexp
+	// canonical maps the type keys of variants to canonical types.
+	canonical map[string]string
+}
+
+// NewMatcher returns a matcher for opts. Invalid patterns are reported with
+// their name or index. Comment types can't make a matcher invalid.
+func NewMatcher(opts MatchOptions) (*Matcher, error) {
+	m := &Matcher{opts: opts, canonical: map[string]string{}}
+
+	for i, p := range opts.Patterns {
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %s", errPattern, name, err)
+		}
+		m.patterns = append(m.patterns, compiledPattern{Pattern: p, re: re})
+	}
+
+	searched := map[string]bool{}
+	for _, t := range opts.Types {
+		searched[m.typeKey(t)] = true
+	}
+
+	types := append([]string{}, opts.Types...)
+	for canonical, variants := range opts.TypeAliases {
+		for _, variant := range variants {
+			m.canonical[m.typeKey(variant)] = m.typeKey(canonical)
+			if searched[m.typeKey(canonical)] {
+				types = append(types, variant)
+			}
+		}
+	}
+
+	if len(types) > 0 {
+		// Longer types first, so FIX-ME is preferred over FIX.
+		sort.SliceStable(types, func(i, j int) bool { return len(types[i]) > len(types[j]) })
+		for i, t := range types {
+			types[i] = typePattern(t)
+		}
+
+		search := `\s*(%s)\s*(?:\(\s*(%s)\s*\))?\s*:\s*(.*)`
+		if opts.Permissive {
+			search = `\s*(%s)\s*(?:\(\s*(%s)\s*\))?(?::|\s*)(.*)`
+		}
+		if !opts.CaseSensitive {
+			search = `(?i)` + search
+		}
+		m.types = regexp.MustCompile(fmt.Sprintf(search, strings.Join(types, "|"), authorListPattern))
+	}
+
+	return m, nil
+}
+
+// typeKey returns the key identifying a type: the type in upper case, or as
+// written if types are case sensitive.
+func (m *Matcher) typeKey(t string) string {
+	if m.opts.CaseSensitive {
+		return t
+	}
+	return strings.ToUpper(t)
+}
+
+// canonicalType returns the type reported for a matched type.
+func (m *Matcher) canonicalType(t string) string {
+	t = m.typeKey(t)
+	if canonical, ok := m.canonical[t]; ok {
+		return canonical
+	}
+	return t
+}
+
+// typePattern returns the regex matching a comment type as a whole word, so
+// TODO matches in "TODO:" but not in "PHOTODOS:" or "my_todo:". Types that
+// start or end with punctuation, such as @todo or XXX!, have no boundary on
+// that side.
+func typePattern(t string) string {
+	pattern := regexp.QuoteMeta(t)
+	if t != "" && isWordByte(t[0]) {
+		pattern = `\b` + pattern
+	}
+	if t != "" && isWordByte(t[len(t)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// isWordByte reports whether b is an ASCII word character, as matched by \w.
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// compilePattern compiles the regex of a pattern and checks its groups.
+func compilePattern(p Pattern) (*regexp.Regexp, error) {
+	if p.Regex == "" {
+		return nil, errors.New("regex is empty")
+	}
+
+	re, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return nil, err
+	}
+
+	hasType := false
+	for _, name := range re.SubexpNames()[1:] {
+		if name == "" {
+			continue
+		}
+		if !patternGroups[name] {
+			return nil, fmt.Errorf("unknown group %q (type, author, text, issue)", name)
+		}
+		hasType = hasType || name == "type"
+	}
+
+	if !hasType && p.Type == "" {
+		return nil, errors.New("regex has no type group and no type is set")
+	}
+
+	return re, nil
+}
+
+// Key identifies the options that affect the comments the matcher finds.
+func (m *Matcher) Key() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%t\x00%t\x00%d\x00%t\x00%s", m.opts.Permissive, m.opts.CaseSensitive, m.opts.MinTextLength, m.opts.RequireComment, strings.Join(m.opts.Types, "\x00"))
+	aliases := make([]string, 0, len(m.canonical))
+	for variant, canonical := range m.canonical {
+		aliases = append(aliases, variant+"\x00"+canonical)
+	}
+	sort.Strings(aliases)
+	fmt.Fprintf(h, "\x01%s", strings.Join(aliases, "\x00"))
+	for _, p := range m.opts.Patterns {
+		fmt.Fprintf(h, "\x01%s\x00%s", p.Regex, p.Type)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Match is a comment found by a Matcher and the position of its type.
+type Match struct {
+	Comment
+	// Start and End are the byte offsets in the line of the comment type as
+	// written, such as a variant of the type or the text matched by a
+	// pattern, before the author and text.
+	Start, End int
+}
+
+// Parse returns the comments in r. The path is used as the file name of the
+// comments and to detect the language, and does not need to exist.
+func (m *Matcher) Parse(r io.Reader, path string) ([]Comment, error) {
+	matches, err := m.Matches(r, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var comments []Comment
+	for _, match := range matches {
+		comments = append(comments, match.Comment)
+	}
+	return comments, nil
+}
+
+// Matches returns the comments in r like Parse, with the position of their
+// type on their line.
+func (m *Matcher) Matches(r io.Reader, path string) ([]Match, error) {
+	var matches []Match
+
+	lang := DetectLanguage(path)
+	language := ""
+	if lang != nil {
+		language = lang.Name
+	}
+	if !m.opts.RequireComment {
+		lang = nil
+	}
+
+	// block is the index of the block comment open at the start of a line,
+	// or -1.
+	block := -1
+
+	scanner := bufio.NewScanner(r)
+	for i := 1; scanner.Scan(); i++ {
+		if match, ok := m.match(scanner.Text(), lang, &block); ok {
+			match.File = path
+			match.Line = i
+			match.Language = language
+			matches = append(matches, match)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// Ignore lines that are too long
+		if scanner.Err() != bufio.ErrTooLong {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// match returns the comment in a line, trying the patterns before the types.
+// If lang is not nil, types are only matched in its comments, and block is
+// updated with the block comment open at the end of the line.
+func (m *Matcher) match(line string, lang *Language, block *int) (Match, bool) {
+	segments := []segment{{line, 0}}
+	if lang != nil {
+		segments = lang.commentSegments(line, block)
+	}
+
+	for _, p := range m.patterns {
+		if index := p.re.FindStringSubmatchIndex(line); index != nil {
+			if c := p.comment(line, index); m.longEnough(c) {
+				c.Type = m.canonicalType(c.Type)
+				start, end := p.typeRange(line, index)
+				return Match{Comment: c, Start: start, End: end}, true
+			}
+		}
+	}
+
+	if m.types == nil {
+		return Match{}, false
+	}
+
+	for _, s := range segments {
+		index := m.types.FindStringSubmatchIndex(s.text)
+		if index == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(strings.TrimPrefix(submatch(s.text, index, 3), ":"))
+		if c := newComment(submatch(s.text, index, 1), submatch(s.text, index, 2), text, ""); m.longEnough(c) {
+			c.Type = m.canonicalType(c.Type)
+			return Match{Comment: c, Start: s.offset + index[2], End: s.offset + index[3]}, true
+		}
+	}
+
+	return Match{}, false
+}
+
+// submatch returns the text of group i of a match found in s, or an empty
+// string if the group didn't match.
+func submatch(s string, index []int, i int) string {
+	if index[2*i] < 0 {
+		return ""
+	}
+	return s[index[2*i]:index[2*i+1]]
+}
+
+// longEnough reports whether the text of a comment has at least
+// MinTextLength characters.
+func (m *Matcher) longEnough(c Comment) bool {
+	return utf8.RuneCountInString(c.Text) >= m.opts.MinTextLength
+}
+
+// comment returns the comment for a match of the pattern in line.
+func (p compiledPattern) comment(line string, index []int) Comment {
+	groups := map[string]string{"type": p.Type}
+	for i, name := range p.re.SubexpNames() {
+		if s := submatch(line, index, i); name != "" && s != "" {
+			groups[name] = s
+		}
+	}
+
+	// Bare numbers are written as #123, like the references found in the
+	// text, so issue trackers recognize them.
+	issue := groups["issue"]
+	if isDigits(issue) {
+		issue = "#" + issue
+	}
+
+	return newComment(groups["type"], groups["author"], strings.TrimSpace(groups["text"]), issue)
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// typeRange returns the byte offsets of the type in a match of the pattern
+// in line: the type group, or else the start of the match up to its first
+// group, such as @todo in "@todo(alice) text".
+func (p compiledPattern) typeRange(line string, index []int) (int, int) {
+	start, end := index[0], index[1]
+	for i, name := range p.re.SubexpNames() {
+		if i == 0 || index[2*i] < 0 {
+			continue
+		}
+		if name == "type" {
+			return index[2*i], index[2*i+1]
+		}
+		if index[2*i] < end {
+			end = index[2*i]
+		}
+	}
+
+	return start, start + len(strings.TrimRight(line[start:end], " \t(:"))
+}
+
+// newComment returns a comment of the given type, authors, text and issue.
+// The issue is taken from the text if empty.
+func newComment(typ, authorList, text, issue string) Comment {
+	authors := parseAuthors(authorList)
+	author := ""
+	if len(authors) > 0 {
+		author = authors[0]
+	}
+
+	if issue == "" {
+		issue = parseIssue(text)
+	}
+
+	return Comment{
+		Type:    typ,
+		Text:    text,
+		Author:  author,
+		Authors: authors,
+		Issue:   issue,
+	}
+}
+
+// SearchPaths searches paths like the SearchPaths function, using the
+// matcher to find comments.
+func (m *Matcher) SearchPaths(paths []string, ignores []string) ([]Comment, error) {
+	return searchPaths(paths, ignores, m)
+}
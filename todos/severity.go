@@ -0,0 +1,84 @@
+package todos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity levels of comments, from least to most severe.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// DefaultSeverity is the severity of comment types without one.
+const DefaultSeverity = SeverityWarning
+
+// DefaultSeverities are the severities of common comment types, used when
+// a type has no configured severity.
+var DefaultSeverities = map[string]string{
+	"TODO":  SeverityInfo,
+	"NOTE":  SeverityInfo,
+	"FIXME": SeverityWarning,
+	"HACK":  SeverityWarning,
+	"XXX":   SeverityWarning,
+	"BUG":   SeverityError,
+}
+
+var errSeverity = errors.New("unknown severity")
+
+// severities are the severity levels in increasing order.
+var severities = []string{SeverityInfo, SeverityWarning, SeverityError}
+
+// ParseSeverity returns the severity level named s, ignoring case.
+func ParseSeverity(s string) (string, error) {
+	for _, severity := range severities {
+		if strings.EqualFold(strings.TrimSpace(s), severity) {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q (%s)", errSeverity, s, strings.Join(severities, ", "))
+}
+
+// SeverityRank returns the rank of a severity level, from 0 for info to 2
+// for error, or -1 if it is not a level.
+func SeverityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// SeverityOf returns the severity of a comment: its Severity, or else the
+// default severity of its type.
+func SeverityOf(c Comment) string {
+	if c.Severity != "" {
+		return c.Severity
+	}
+	if severity, ok := DefaultSeverities[strings.ToUpper(c.Type)]; ok {
+		return severity
+	}
+	return DefaultSeverity
+}
+
+// AssignSeverities sets the severity of each comment from its type, using
+// the severities given by type before the DefaultSeverities. Types are
+// matched ignoring case.
+func AssignSeverities(comments []Comment, byType map[string]string) {
+	upper := make(map[string]string, len(byType))
+	for t, severity := range byType {
+		upper[strings.ToUpper(t)] = severity
+	}
+
+	for i := range comments {
+		if severity, ok := upper[strings.ToUpper(comments[i].Type)]; ok {
+			comments[i].Severity = severity
+			continue
+		}
+		comments[i].Severity = SeverityOf(Comment{Type: comments[i].Type})
+	}
+}
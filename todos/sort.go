@@ -0,0 +1,175 @@
+package todos
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortKey is a field to sort comments by.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// SortFields are the fields comments can be sorted by.
+var SortFields = []string{"author", "file", "line", "type", "text", "issue", "language", "owner", "severity"}
+
+// ParseSortKeys parses a comma-separated list of fields to sort by, each
+// optionally followed by ":asc" or ":desc", such as "type,author:desc,file".
+func ParseSortKeys(spec string) ([]SortKey, error) {
+	keys := []SortKey{}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field, order, _ := strings.Cut(part, ":")
+		key := SortKey{Field: strings.ToLower(strings.TrimSpace(field))}
+
+		switch strings.ToLower(strings.TrimSpace(order)) {
+		case "", "asc":
+		case "desc":
+			key.Desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort order %q for %s (asc, desc)", order, key.Field)
+		}
+
+		if !validSortField(key.Field) {
+			return nil, fmt.Errorf("unknown sort field %q (%s)", key.Field, strings.Join(SortFields, ", "))
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func validSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// SortComments sorts the comments by each key in turn. The sort is stable
+// and comments that are equal for every key are ordered by file and line, so
+// the order is the same on every run. Files are compared in natural order,
+// so file2.go comes before file10.go.
+func SortComments(comments []Comment, keys []SortKey) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := &comments[i], &comments[j]
+
+		for _, key := range keys {
+			if c := compareField(a, b, key.Field); c != 0 {
+				if key.Desc {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+
+		if c := compareField(a, b, "file"); c != 0 {
+			return c < 0
+		}
+		return a.Line < b.Line
+	})
+}
+
+// compareField compares a field of two comments, returning -1, 0 or 1.
+func compareField(a, b *Comment, field string) int {
+	switch field {
+	case "author":
+		return strings.Compare(strings.Join(Authors(*a), ","), strings.Join(Authors(*b), ","))
+	case "file":
+		return compareNatural(a.File, b.File)
+	case "line":
+		switch {
+		case a.Line < b.Line:
+			return -1
+		case a.Line > b.Line:
+			return 1
+		}
+		return 0
+	case "type":
+		return strings.Compare(a.Type, b.Type)
+	case "text":
+		return strings.Compare(a.Text, b.Text)
+	case "issue":
+		return compareNatural(a.Issue, b.Issue)
+	case "language":
+		return strings.Compare(a.Language, b.Language)
+	case "owner":
+		return strings.Compare(strings.Join(a.Owners, ","), strings.Join(b.Owners, ","))
+	case "severity":
+		// Severities sort from info to error, not by name.
+		x, y := SeverityRank(SeverityOf(*a)), SeverityRank(SeverityOf(*b))
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
+
+// compareNatural compares strings treating runs of digits as numbers.
+func compareNatural(a, b string) int {
+	x, y := a, b
+
+	for x != "" && y != "" {
+		if isDigit(x[0]) && isDigit(y[0]) {
+			i, j := digitRun(x), digitRun(y)
+			nx, ny := strings.TrimLeft(x[:i], "0"), strings.TrimLeft(y[:j], "0")
+
+			if len(nx) != len(ny) {
+				if len(nx) < len(ny) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(nx, ny); c != 0 {
+				return c
+			}
+
+			x, y = x[i:], y[j:]
+			continue
+		}
+
+		if x[0] != y[0] {
+			if x[0] < y[0] {
+				return -1
+			}
+			return 1
+		}
+		x, y = x[1:], y[1:]
+	}
+
+	if len(x) != len(y) {
+		if len(x) < len(y) {
+			return -1
+		}
+		return 1
+	}
+
+	// Numbers that only differ in leading zeros.
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digitRun(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
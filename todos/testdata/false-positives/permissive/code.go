@@ -0,0 +1,13 @@
+package code
+
+import "fmt"
+
+type todoList struct{ items []string }
+
+func todo(items []string) {
+	todo := todoList{items: items}
+	fmt.Println("todo list:", todo.items)
+	fmt.Println("fixme: not a comment")
+}
+
+// todo
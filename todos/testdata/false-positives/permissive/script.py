@@ -0,0 +1,3 @@
+todo = []
+print("todo: not a comment")  # fixme
+message = "fixme: a string, not a comment"
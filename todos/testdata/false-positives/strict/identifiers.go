@@ -0,0 +1,11 @@
+package identifiers
+
+// Markers inside longer words are not comments: PHOTOTODO: one, XFIXME: two.
+// mastodon_todo: three
+// autodoc: four
+// TODOS: five
+
+var my_todo = "undertodo: six"
+
+// TODO_LIST: seven
+func FIXMEup() {}
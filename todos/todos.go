@@ -2,7 +2,6 @@ package todos
 
 import (
 	"bufio"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -20,33 +19,63 @@ type Comment struct {
 	Type   string `json:"type"`
 	Text   string `json:"text"`
 	Author string `json:"author"`
+	// Authors are all the authors or assignees of the comment, such as
+	// alice and bob for TODO(alice, bob). Author is the first of them.
+	Authors []string `json:"authors,omitempty"`
+	Issue   string   `json:"issue,omitempty"`
+	// Severity is the severity level of the comment's type: info, warning
+	// or error.
+	Severity string `json:"severity,omitempty"`
+
+	Language string `json:"language,omitempty"`
+	// Owners are the code owners of the file, from a CODEOWNERS file.
+	Owners []string `json:"owners,omitempty"`
 }
 
+// authorPattern matches an author: a name, an @-mention, an email or a team
+// slug such as team/platform or @acme/platform. authorListPattern matches a
+// list of authors separated by commas or spaced slashes.
+const (
+	authorPattern     = `@?[\w.+-]+(?:@[\w.-]+)?(?:/[\w.-]+)*`
+	authorListPattern = authorPattern + `(?:\s*,\s*` + authorPattern + `|\s+/\s+` + authorPattern + `)*`
+)
+
+// authorSepRegex matches the separators in a list of authors.
+var authorSepRegex = regexp.MustCompile(`\s*,\s*|\s+/\s+`)
+
+// issueRegex matches issue references such as #123 or PROJ-45 in comment text.
+var issueRegex = regexp.MustCompile(`(?:^|[^\w#/-])(#\d+|[A-Z][A-Z0-9_]+-\d+)\b`)
+
 // Search searches a directory for comments
 func Search(dir string, commentTypes []string, ignores []string, permissive bool) ([]Comment, error) {
-	searchHidden, ignores := removeHiddenIgnore(ignores)
+	return SearchPaths([]string{dir}, commentTypes, ignores, permissive)
+}
 
-	commentsChan := make(chan []Comment)
-	var wg sync.WaitGroup
+// SearchPaths searches directories and files for comments. A file reached
+// through more than one path, such as a file inside a directory that is also
+// searched, is only searched once.
+func SearchPaths(paths []string, commentTypes []string, ignores []string, permissive bool) ([]Comment, error) {
+	m, err := NewMatcher(MatchOptions{Types: commentTypes, Permissive: permissive})
+	if err != nil {
+		return nil, err
+	}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if info == nil {
-			return nil
-		}
+	return searchPaths(paths, ignores, m)
+}
 
-		if info.IsDir() {
-			if !searchHidden && strings.HasPrefix(info.Name(), ".") && info.Name() != "." {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// searchPaths searches paths for comments found by m
+func searchPaths(paths []string, ignores []string, m *Matcher) ([]Comment, error) {
+	files, err := Files(paths, ignores)
+	if err != nil {
+		return nil, err
+	}
 
-		if shouldIgnoreFile(info, ignores, path, searchHidden) {
-			return nil
-		}
+	commentsChan := make(chan []Comment)
+	var wg sync.WaitGroup
 
+	for _, path := range files {
 		wg.Add(1)
-		go func() {
+		go func(path string) {
 			defer wg.Done()
 
 			// Open the file and search for comments
@@ -61,20 +90,14 @@ func Search(dir string, commentTypes []string, ignores []string, permissive bool
 			}
 			defer file.Close()
 
-			fileComments, parseErr := Parse(file, path, commentTypes, permissive)
+			fileComments, parseErr := m.Parse(file, path)
 			if parseErr != nil {
 				commentsChan <- []Comment{}
 				return
 			}
 
 			commentsChan <- fileComments
-		}()
-
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
+		}(path)
 	}
 
 	go func() {
@@ -90,6 +113,69 @@ func Search(dir string, commentTypes []string, ignores []string, permissive bool
 	return comments, nil
 }
 
+// Files returns the files to search in paths, which may be directories or
+// files. Directories are walked recursively and ignored files are skipped.
+// Files are returned in the order they are found, without duplicates.
+func Files(paths []string, ignores []string) ([]string, error) {
+	searchHidden, ignores := removeHiddenIgnore(append([]string{}, ignores...))
+
+	files := []string{}
+	seen := map[string]bool{}
+
+	add := func(path string) {
+		key := path
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		files = append(files, path)
+	}
+
+	for _, root := range paths {
+		root = filepath.Clean(root)
+
+		rootInfo, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if !rootInfo.IsDir() {
+			if !shouldIgnoreFile(rootInfo, ignores, root, searchHidden) {
+				add(root)
+			}
+			continue
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if info == nil {
+				return nil
+			}
+
+			if info.IsDir() {
+				// Directories given explicitly are searched even if hidden.
+				if path != root && !searchHidden && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !shouldIgnoreFile(info, ignores, path, searchHidden) {
+				add(path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
 // ParseFile parses a file for comments
 func removeHiddenIgnore(ignores []string) (bool, []string) {
 	searchHidden := true
@@ -126,43 +212,155 @@ func shouldIgnoreFile(info os.FileInfo, ignores []string, path string, searchHid
 	return false
 }
 
-// Parse parses the specified file and returns a slice of comments.
-func Parse(r io.Reader, path string, commentTypes []string, permissive bool) ([]Comment, error) {
-	search := `(?i)\s*(%s)\s*(?:\(([\w.-]+)\))?\s*:\s*(.*)`
-	if permissive {
-		search = `(?i)\s*(%s)\s*(?:\(([\w.-]+)\))?(?::|\s*)(.*)`
+// IgnoredPath reports whether the file at path, relative to a searched
+// directory, is ignored by the gitignore style ignores or is hidden. Unlike
+// Files it does not access the file system, so it can be used for files that
+// are not on disk, such as the files in a git tree.
+func IgnoredPath(path string, ignores []string) bool {
+	searchHidden, ignores := removeHiddenIgnore(append([]string{}, ignores...))
+
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+
+	if !searchHidden {
+		for _, part := range parts {
+			if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+				return true
+			}
+		}
 	}
 
-	// Define regular expression to match the specified comment types
-	commentRegex := regexp.MustCompile(fmt.Sprintf(search, strings.Join(commentTypes, "|")))
+	for _, pattern := range ignores {
+		if matchIgnorePattern(pattern, parts) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Create a slice to hold the comments
-	var comments []Comment
+// matchIgnorePattern matches a gitignore style pattern against the parts of
+// a path. A pattern without a slash matches any part, such as a directory
+// name or the file name. A pattern with a slash matches the leading parts, or
+// any parts if it starts with "**/". Negated patterns never match.
+func matchIgnorePattern(pattern string, parts []string) bool {
+	pattern = strings.TrimSpace(filepath.ToSlash(pattern))
+	if pattern == "" || strings.HasPrefix(pattern, "#") || strings.HasPrefix(pattern, "!") {
+		return false
+	}
+	pattern = strings.Trim(pattern, "/")
 
-	scanner := bufio.NewScanner(r)
-	for i := 1; scanner.Scan(); i++ {
-		line := scanner.Text()
-		if matches := commentRegex.FindStringSubmatch(line); matches != nil {
-			commentType := strings.ToUpper(matches[1])
-			author := matches[2]
-			commentText := strings.TrimSpace(strings.TrimPrefix(matches[3], ":"))
-			comment := Comment{
-				File:   path,
-				Line:   i,
-				Type:   commentType,
-				Text:   commentText,
-				Author: author,
+	if !strings.Contains(pattern, "/") {
+		for _, part := range parts {
+			if ok, _ := filepath.Match(pattern, part); ok {
+				return true
 			}
-			comments = append(comments, comment)
 		}
+		return false
 	}
-	if err := scanner.Err(); err != nil {
-		// Ignore lines that are too long
-		if scanner.Err() != bufio.ErrTooLong {
-			return nil, err
+
+	starts := 1
+	if strings.HasPrefix(pattern, "**/") {
+		pattern = strings.TrimPrefix(pattern, "**/")
+		starts = len(parts)
+	}
+
+	for start := 0; start < starts; start++ {
+		for end := start + 1; end <= len(parts); end++ {
+			if ok, _ := filepath.Match(pattern, strings.Join(parts[start:end], "/")); ok {
+				return true
+			}
 		}
 	}
-	return comments, nil
+	return false
+}
+
+// Parse parses the specified file and returns a slice of comments. The path
+// is used as the file name of the comments and to detect the language, and
+// does not need to exist.
+func Parse(r io.Reader, path string, commentTypes []string, permissive bool) ([]Comment, error) {
+	m, err := NewMatcher(MatchOptions{Types: commentTypes, Permissive: permissive})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Parse(r, path)
+}
+
+// Authors returns the authors of a comment. Comments without Authors, such
+// as those read from reports of older versions, return their Author.
+func Authors(c Comment) []string {
+	if len(c.Authors) > 0 {
+		return c.Authors
+	}
+	if c.Author != "" {
+		return []string{c.Author}
+	}
+	return []string{}
+}
+
+// NoAuthor is the name comments without an author are counted and grouped
+// under.
+const NoAuthor = "(no author)"
+
+// AuthorsOrNone returns the authors of a comment, or NoAuthor if it has none.
+// Counts by author use it, so a comment with several authors is counted for
+// each of them.
+func AuthorsOrNone(c Comment) []string {
+	if authors := Authors(c); len(authors) > 0 {
+		return authors
+	}
+	return []string{NoAuthor}
+}
+
+// MapAuthors returns the comment with each of its authors replaced by
+// canonical(author). Authors that map to the same name are only kept once.
+// The Authors slice of c is not modified, so comments shared with a cache
+// can be mapped.
+func MapAuthors(c Comment, canonical func(string) string) Comment {
+	if c.Author != "" {
+		c.Author = canonical(c.Author)
+	}
+	if len(c.Authors) == 0 {
+		return c
+	}
+
+	authors := make([]string, 0, len(c.Authors))
+	seen := map[string]bool{}
+	for _, author := range c.Authors {
+		author = canonical(author)
+		if !seen[author] {
+			seen[author] = true
+			authors = append(authors, author)
+		}
+	}
+	c.Authors = authors
+
+	return c
+}
+
+// parseAuthors splits a list of authors. The @ of a mention of a single user
+// is removed, so @alice and alice are the same author, while team mentions
+// such as @acme/platform are kept as written.
+func parseAuthors(list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	authors := []string{}
+	for _, author := range authorSepRegex.Split(strings.TrimSpace(list), -1) {
+		if !strings.Contains(author, "/") {
+			author = strings.TrimPrefix(author, "@")
+		}
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+// parseIssue returns the first issue reference in text.
+func parseIssue(text string) string {
+	if matches := issueRegex.FindStringSubmatch(text); matches != nil {
+		return matches[1]
+	}
+	return ""
 }
 
 // ParseGitignore parses the .gitignore file in the specified directory and returns a slice of
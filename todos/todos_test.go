@@ -1,8 +1,16 @@
 package todos_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/euforic/todos/todos"
 	"github.com/google/go-cmp/cmp"
@@ -33,25 +41,29 @@ func TestSearch(t *testing.T) {
 			commentType: []string{"TODO", "FIXME"},
 			want: []todos.Comment{
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   5,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "",
+					File:     "testdata/single-file-match/test.go",
+					Line:     5,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   11,
-					Type:   "FIXME",
-					Text:   "do something",
-					Author: "",
+					File:     "testdata/single-file-match/test.go",
+					Line:     11,
+					Type:     "FIXME",
+					Text:     "do something",
+					Author:   "",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   14,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "user",
+					File:     "testdata/single-file-match/test.go",
+					Line:     14,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "user",
+					Authors:  []string{"user"},
+					Language: "Go",
 				},
 			},
 			wantErr: false,
@@ -64,38 +76,45 @@ func TestSearch(t *testing.T) {
 			permissive:  true,
 			want: []todos.Comment{
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   5,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "",
+					File:     "testdata/single-file-match/test.go",
+					Line:     5,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   11,
-					Type:   "FIXME",
-					Text:   "do something",
-					Author: "",
+					File:     "testdata/single-file-match/test.go",
+					Line:     11,
+					Type:     "FIXME",
+					Text:     "do something",
+					Author:   "",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   14,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "user",
+					File:     "testdata/single-file-match/test.go",
+					Line:     14,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "user",
+					Authors:  []string{"user"},
+					Language: "Go",
 				},
 				{
-					File: "testdata/single-file-match/test.go",
-					Line: 16,
-					Type: "TODO",
-					Text: "this isn't the right way to do this",
+					File:     "testdata/single-file-match/test.go",
+					Line:     16,
+					Type:     "TODO",
+					Text:     "this isn't the right way to do this",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/single-file-match/test.go",
-					Line:   17,
-					Type:   "TODO",
-					Text:   "this is a todo",
-					Author: "user",
+					File:     "testdata/single-file-match/test.go",
+					Line:     17,
+					Type:     "TODO",
+					Text:     "this is a todo",
+					Author:   "user",
+					Authors:  []string{"user"},
+					Language: "Go",
 				},
 			},
 			wantErr: false,
@@ -107,39 +126,47 @@ func TestSearch(t *testing.T) {
 			commentType: []string{"TODO", "FIXME"},
 			want: []todos.Comment{
 				{
-					File:   "testdata/multiple-file-matches/file.yml",
-					Line:   17,
-					Type:   "FIXME",
-					Text:   "do something",
-					Author: "user",
+					File:     "testdata/multiple-file-matches/file.yml",
+					Line:     17,
+					Type:     "FIXME",
+					Text:     "do something",
+					Author:   "user",
+					Authors:  []string{"user"},
+					Language: "YAML",
 				},
 				{
-					File:   "testdata/multiple-file-matches/file.yml",
-					Line:   30,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "",
+					File:     "testdata/multiple-file-matches/file.yml",
+					Line:     30,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "",
+					Language: "YAML",
 				},
 				{
-					File:   "testdata/multiple-file-matches/file1.go",
-					Line:   5,
-					Type:   "FIXME",
-					Text:   "fix this",
-					Author: "",
+					File:     "testdata/multiple-file-matches/file1.go",
+					Line:     5,
+					Type:     "FIXME",
+					Text:     "fix this",
+					Author:   "",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/multiple-file-matches/file2.go",
-					Line:   5,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "john.doe",
+					File:     "testdata/multiple-file-matches/file2.go",
+					Line:     5,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "john.doe",
+					Authors:  []string{"john.doe"},
+					Language: "Go",
 				},
 				{
-					File:   "testdata/multiple-file-matches/file2.go",
-					Line:   8,
-					Type:   "TODO",
-					Text:   "this is a todo",
-					Author: "euforic",
+					File:     "testdata/multiple-file-matches/file2.go",
+					Line:     8,
+					Type:     "TODO",
+					Text:     "this is a todo",
+					Author:   "euforic",
+					Authors:  []string{"euforic"},
+					Language: "Go",
 				},
 			},
 			wantErr: false,
@@ -151,25 +178,30 @@ func TestSearch(t *testing.T) {
 			commentType: []string{"TODO", "FIXME"},
 			want: []todos.Comment{
 				{
-					File:   "testdata/multiple-file-matches/file1.go",
-					Line:   5,
-					Type:   "FIXME",
-					Text:   "fix this",
-					Author: "",
+					File:     "testdata/multiple-file-matches/file1.go",
+					Line:     5,
+					Type:     "FIXME",
+					Text:     "fix this",
+					Author:   "",
+					Language: "Go",
 				},
 				{
-					File:   "testdata/multiple-file-matches/file2.go",
-					Line:   5,
-					Type:   "TODO",
-					Text:   "do something",
-					Author: "john.doe",
+					File:     "testdata/multiple-file-matches/file2.go",
+					Line:     5,
+					Type:     "TODO",
+					Text:     "do something",
+					Author:   "john.doe",
+					Authors:  []string{"john.doe"},
+					Language: "Go",
 				},
 				{
-					File:   "testdata/multiple-file-matches/file2.go",
-					Line:   8,
-					Type:   "TODO",
-					Text:   "this is a todo",
-					Author: "euforic",
+					File:     "testdata/multiple-file-matches/file2.go",
+					Line:     8,
+					Type:     "TODO",
+					Text:     "this is a todo",
+					Author:   "euforic",
+					Authors:  []string{"euforic"},
+					Language: "Go",
 				},
 			},
 			wantErr: false,
@@ -208,3 +240,959 @@ func TestSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchPaths(t *testing.T) {
+	paths := []string{
+		"testdata/multiple-file-matches",
+		"testdata/multiple-file-matches/file1.go",
+		"./testdata/multiple-file-matches/file2.go",
+		"testdata/single-file-match/test.go",
+	}
+
+	got, err := todos.SearchPaths(paths, []string{"TODO", "FIXME"}, []string{"*.yml"}, false)
+	if err != nil {
+		t.Fatalf("SearchPaths() error = %v", err)
+	}
+
+	sort.Slice(got, func(i, j int) bool {
+		if got[i].File == got[j].File {
+			return got[i].Line < got[j].Line
+		}
+		return got[i].File < got[j].File
+	})
+
+	want := []todos.Comment{
+		{File: "testdata/multiple-file-matches/file1.go", Line: 5, Type: "FIXME", Text: "fix this", Language: "Go"},
+		{File: "testdata/multiple-file-matches/file2.go", Line: 5, Type: "TODO", Text: "do something", Author: "john.doe", Authors: []string{"john.doe"}, Language: "Go"},
+		{File: "testdata/multiple-file-matches/file2.go", Line: 8, Type: "TODO", Text: "this is a todo", Author: "euforic", Authors: []string{"euforic"}, Language: "Go"},
+		{File: "testdata/single-file-match/test.go", Line: 5, Type: "TODO", Text: "do something", Language: "Go"},
+		{File: "testdata/single-file-match/test.go", Line: 11, Type: "FIXME", Text: "do something", Language: "Go"},
+		{File: "testdata/single-file-match/test.go", Line: 14, Type: "TODO", Text: "do something", Author: "user", Authors: []string{"user"}, Language: "Go"},
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("SearchPaths() \n%s", cmp.Diff(got, want))
+	}
+
+	if _, err := todos.SearchPaths([]string{"testdata/does-not-exist"}, []string{"TODO"}, nil, false); err == nil {
+		t.Errorf("SearchPaths() expected error for missing path")
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := "#!/bin/sh\n# TODO(ops): rotate logs\necho done\n"
+
+	got, err := todos.Parse(strings.NewReader(src), "scripts/unsaved.sh", []string{"TODO"}, false)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []todos.Comment{
+		{File: "scripts/unsaved.sh", Line: 2, Type: "TODO", Text: "rotate logs", Author: "ops", Authors: []string{"ops"}, Language: "Shell"},
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("Parse() \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestParseAuthors(t *testing.T) {
+	tests := []struct {
+		line       string
+		permissive bool
+		want       []string
+		wantText   string
+	}{
+		{line: "// TODO(alice): a", want: []string{"alice"}, wantText: "a"},
+		{line: "// TODO(alice,bob): a", want: []string{"alice", "bob"}, wantText: "a"},
+		{line: "// TODO( alice , bob ): a", want: []string{"alice", "bob"}, wantText: "a"},
+		{line: "// TODO(@alice): a", want: []string{"alice"}, wantText: "a"},
+		{line: "// TODO(john.doe@example.com): a", want: []string{"john.doe@example.com"}, wantText: "a"},
+		{line: "// TODO(team/platform): a", want: []string{"team/platform"}, wantText: "a"},
+		{line: "// TODO(@acme/platform, @bob): a", want: []string{"@acme/platform", "bob"}, wantText: "a"},
+		{line: "// TODO(alice / bob): a", want: []string{"alice", "bob"}, wantText: "a"},
+		{line: "// TODO: a", want: nil, wantText: "a"},
+		{line: "// TODO(alice, bob) a", permissive: true, want: []string{"alice", "bob"}, wantText: "a"},
+		{line: "// TODO(@alice/bob): a", permissive: true, want: []string{"@alice/bob"}, wantText: "a"},
+	}
+
+	for _, tt := range tests {
+		got, err := todos.Parse(strings.NewReader(tt.line), "main.go", []string{"TODO"}, tt.permissive)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.line, err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("Parse(%q) found %d comments, want 1", tt.line, len(got))
+		}
+
+		if !cmp.Equal(got[0].Authors, tt.want) || got[0].Text != tt.wantText {
+			t.Errorf("Parse(%q) = %q %q, want %q %q", tt.line, got[0].Authors, got[0].Text, tt.want, tt.wantText)
+		}
+		wantAuthor := ""
+		if len(tt.want) > 0 {
+			wantAuthor = tt.want[0]
+		}
+		if got[0].Author != wantAuthor {
+			t.Errorf("Parse(%q) Author = %q, want %q", tt.line, got[0].Author, wantAuthor)
+		}
+	}
+
+	// Parenthesized text that isn't a list of authors is not a comment in
+	// strict mode.
+	got, err := todos.Parse(strings.NewReader("// TODO(see below): a"), "main.go", []string{"TODO"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Parse() = %v, want no comments", got)
+	}
+}
+
+func TestMapAuthors(t *testing.T) {
+	canonical := func(author string) string {
+		switch author {
+		case "jdoe", "john.doe":
+			return "John Doe"
+		}
+		return author
+	}
+
+	tests := []struct {
+		name        string
+		comment     todos.Comment
+		wantAuthor  string
+		wantAuthors []string
+	}{
+		{name: "Single", comment: todos.Comment{Author: "jdoe", Authors: []string{"jdoe"}}, wantAuthor: "John Doe", wantAuthors: []string{"John Doe"}},
+		{name: "Duplicates", comment: todos.Comment{Author: "jdoe", Authors: []string{"jdoe", "john.doe"}}, wantAuthor: "John Doe", wantAuthors: []string{"John Doe"}},
+		{name: "Unmapped", comment: todos.Comment{Author: "bob", Authors: []string{"bob", "jdoe", "john.doe"}}, wantAuthor: "bob", wantAuthors: []string{"bob", "John Doe"}},
+		{name: "AuthorOnly", comment: todos.Comment{Author: "john.doe"}, wantAuthor: "John Doe"},
+		{name: "None", comment: todos.Comment{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := append([]string(nil), tt.comment.Authors...)
+			got := todos.MapAuthors(tt.comment, canonical)
+			if got.Author != tt.wantAuthor || !cmp.Equal(got.Authors, tt.wantAuthors) {
+				t.Errorf("MapAuthors() = %q %q, want %q %q", got.Author, got.Authors, tt.wantAuthor, tt.wantAuthors)
+			}
+			if !cmp.Equal(tt.comment.Authors, before) {
+				t.Errorf("MapAuthors() modified Authors to %q", tt.comment.Authors)
+			}
+		})
+	}
+}
+
+func TestMatcherPatterns(t *testing.T) {
+	m, err := todos.NewMatcher(todos.MatchOptions{
+		Types: []string{"TODO", "XXX!"},
+		Patterns: []todos.Pattern{
+			{Name: "phpdoc", Regex: `@(?P<type>todo)\s+(?P<text>.*)`},
+			{Name: "jira", Regex: `(?P<type>TODO)-(?P<issue>\d+):\s*(?P<text>.*)`},
+			{Name: "rust", Regex: `todo!\((?:"(?P<text>[^"]*)")?\)`, Type: "TODO"},
+			{Name: "owner", Regex: `HACK\[(?P<author>[^\]]+)\]\s*(?P<text>.*)`, Type: "hack"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := strings.Join([]string{
+		" * @todo handle errors",
+		"// TODO-123: ship it",
+		`    todo!("parse the header")`,
+		"    todo!()",
+		"# HACK[alice, bob] works around #7",
+		"// XXX!: fix before release",
+		"// XXX: not a type",
+		"// TODO(carol): plain",
+	}, "\n")
+
+	got, err := m.Parse(strings.NewReader(src), "lib.rs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []todos.Comment{
+		{File: "lib.rs", Line: 1, Type: "TODO", Text: "handle errors", Language: "Rust"},
+		{File: "lib.rs", Line: 2, Type: "TODO", Text: "ship it", Issue: "#123", Language: "Rust"},
+		{File: "lib.rs", Line: 3, Type: "TODO", Text: "parse the header", Language: "Rust"},
+		{File: "lib.rs", Line: 4, Type: "TODO", Language: "Rust"},
+		{File: "lib.rs", Line: 5, Type: "HACK", Text: "works around #7", Author: "alice", Authors: []string{"alice", "bob"}, Issue: "#7", Language: "Rust"},
+		{File: "lib.rs", Line: 6, Type: "XXX!", Text: "fix before release", Language: "Rust"},
+		{File: "lib.rs", Line: 8, Type: "TODO", Text: "plain", Author: "carol", Authors: []string{"carol"}, Language: "Rust"},
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("Parse() \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern todos.Pattern
+	}{
+		{name: "Syntax", pattern: todos.Pattern{Regex: `TODO(`, Type: "TODO"}},
+		{name: "Empty", pattern: todos.Pattern{Type: "TODO"}},
+		{name: "UnknownGroup", pattern: todos.Pattern{Regex: `TODO: (?P<txt>.*)`, Type: "TODO"}},
+		{name: "NoType", pattern: todos.Pattern{Regex: `TODO: (?P<text>.*)`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := todos.NewMatcher(todos.MatchOptions{Patterns: []todos.Pattern{tt.pattern}})
+			if err == nil {
+				t.Error("NewMatcher() error = nil")
+			}
+		})
+	}
+}
+
+func TestMatcherTypes(t *testing.T) {
+	aliases := map[string][]string{
+		"FIXME": {"FIX-ME", "FixMe", "BUG"},
+		"HACK":  {"XXX"},
+	}
+	src := strings.Join([]string{
+		"// TODO: upper",
+		"// todo: lower",
+		"// FIX-ME: dashed",
+		"// FixMe: camel",
+		"// bug: lower bug",
+		"// XXX: not searched",
+		"// FIXME: upper",
+	}, "\n")
+
+	tests := []struct {
+		name string
+		opts todos.MatchOptions
+		want []string
+	}{
+		{
+			name: "Insensitive",
+			opts: todos.MatchOptions{Types: []string{"TODO", "FIXME"}, TypeAliases: aliases},
+			want: []string{"1 TODO", "2 TODO", "3 FIXME", "4 FIXME", "5 FIXME", "7 FIXME"},
+		},
+		{
+			name: "Sensitive",
+			opts: todos.MatchOptions{Types: []string{"TODO", "FIXME"}, TypeAliases: aliases, CaseSensitive: true},
+			want: []string{"1 TODO", "3 FIXME", "4 FIXME", "7 FIXME"},
+		},
+		{
+			name: "SensitiveLowerType",
+			opts: todos.MatchOptions{Types: []string{"todo"}, CaseSensitive: true},
+			want: []string{"2 todo"},
+		},
+		{
+			name: "Pattern",
+			opts: todos.MatchOptions{
+				TypeAliases: aliases,
+				Patterns:    []todos.Pattern{{Regex: `(?P<type>XXX): (?P<text>.*)`}},
+			},
+			want: []string{"6 HACK"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := todos.NewMatcher(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			comments, err := m.Parse(strings.NewReader(src), "a.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, c := range comments {
+				got = append(got, fmt.Sprintf("%d %s", c.Line, c.Type))
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFalsePositives(t *testing.T) {
+	// Each directory of testdata/false-positives holds files without
+	// comments, which must not match with the given options.
+	tests := []struct {
+		dir  string
+		opts todos.MatchOptions
+	}{
+		{dir: "strict", opts: todos.MatchOptions{}},
+		{dir: "permissive", opts: todos.MatchOptions{RequireComment: true, MinTextLength: 3}},
+		{dir: "permissive", opts: todos.MatchOptions{Permissive: true, RequireComment: true, MinTextLength: 3}},
+	}
+
+	for _, tt := range tests {
+		tt.opts.Types = []string{"TODO", "FIXME"}
+		m, err := todos.NewMatcher(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := m.SearchPaths([]string{filepath.Join("testdata", "false-positives", tt.dir)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range got {
+			t.Errorf("%s (permissive %t): %s:%d matched %s %q", tt.dir, tt.opts.Permissive, c.File, c.Line, c.Type, c.Text)
+		}
+	}
+}
+
+func TestMatcherRequireComment(t *testing.T) {
+	m, err := todos.NewMatcher(todos.MatchOptions{Types: []string{"TODO"}, RequireComment: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		src  string
+		want []string
+	}{
+		{path: "a.go", src: "x := 1 // TODO: trailing\ns := \"TODO: string\"", want: []string{"trailing"}},
+		{path: "a.go", src: "/* TODO: one line */\n/**\n * TODO: inside\n */\ntodo := 2", want: []string{"one line", "inside"}},
+		{path: "a.lua", src: "--[[ TODO: block\nTODO: still block ]] TODO: code\n-- TODO: line", want: []string{"block", "still block", "line"}},
+		{path: "a.html", src: "<p>TODO: text</p><!-- TODO: comment -->", want: []string{"comment"}},
+		{path: "a.py", src: "def f():\n    \"\"\"TODO: docstring\"\"\"\n    x = 'TODO: no'", want: []string{"docstring"}},
+		{path: "notes.txt", src: "TODO: unknown language", want: []string{"unknown language"}},
+	}
+
+	for _, tt := range tests {
+		comments, err := m.Parse(strings.NewReader(tt.src), tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		for _, c := range comments {
+			got = append(got, c.Text)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherMatches(t *testing.T) {
+	m, err := todos.NewMatcher(todos.MatchOptions{Types: []string{"TODO"}, RequireComment: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := m.Matches(strings.NewReader("s := \"TODO: no\" /* a */ // todo: yes\n"), "main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 1 || matches[0].Start != 27 || matches[0].End != 31 || matches[0].Text != "yes" {
+		t.Errorf("Matches() = %+v, want the todo at 27:31", matches)
+	}
+}
+
+func TestMatcherBoundaries(t *testing.T) {
+	m, err := todos.NewMatcher(todos.MatchOptions{Types: []string{"TODO", "@fixme"}, Permissive: true, MinTextLength: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := strings.Join([]string{
+		"// PHOTOTODOS: no",
+		"// TODO_LIST: no",
+		"// TODO: yes",
+		"// (TODO) parenthesized",
+		"// x@fixme: punctuation",
+		"// TODO a",
+	}, "\n")
+
+	comments, err := m.Parse(strings.NewReader(src), "a.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []int{}
+	for _, c := range comments {
+		got = append(got, c.Line)
+	}
+	if want := []int{3, 4, 5}; !cmp.Equal(got, want) {
+		t.Errorf("Parse() lines = %v, want %v", got, want)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "main.go", want: "Go"},
+		{path: "web/App.TSX", want: "TypeScript"},
+		{path: "build/Dockerfile", want: "Dockerfile"},
+		{path: "Makefile.inc", want: "Makefile"},
+		{path: "config/.todos.yml", want: "YAML"},
+		{path: "LICENSE", want: ""},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if lang := todos.DetectLanguage(tt.path); lang != nil {
+			got = lang.Name
+		}
+
+		if got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, mod time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("a.go", "// TODO: one\n// TODO: two\n", start)
+	write("b.go", "// FIXME: three\n", start)
+
+	w := todos.NewWatcher([]string{dir}, []string{"TODO", "FIXME"}, nil, false)
+
+	added, removed, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(added) != 3 || len(removed) != 0 {
+		t.Fatalf("first Scan() = %v, %v", added, removed)
+	}
+
+	// Moving a comment to another line is not a change, editing one is.
+	write("a.go", "package a\n\n// TODO: two\n// TODO: one (edited)\n", start.Add(time.Minute))
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed, err = w.Scan()
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+
+	aFile, bFile := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	wantAdded := []todos.Comment{{File: aFile, Line: 4, Type: "TODO", Text: "one (edited)", Language: "Go"}}
+	wantRemoved := []todos.Comment{
+		{File: aFile, Line: 1, Type: "TODO", Text: "one", Language: "Go"},
+		{File: bFile, Line: 1, Type: "FIXME", Text: "three", Language: "Go"},
+	}
+
+	if !cmp.Equal(added, wantAdded) {
+		t.Errorf("Scan() added \n%s", cmp.Diff(added, wantAdded))
+	}
+	if !cmp.Equal(removed, wantRemoved) {
+		t.Errorf("Scan() removed \n%s", cmp.Diff(removed, wantRemoved))
+	}
+
+	if got := w.Comments(); len(got) != 2 || got[0].Text != "two" {
+		t.Errorf("Comments() = %v", got)
+	}
+
+	added, removed, err = w.Scan()
+	if err != nil || len(added) != 0 || len(removed) != 0 {
+		t.Errorf("unchanged Scan() = %v, %v, %v", added, removed, err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.Mkdir(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(dir, ".todos-cache")
+
+	write := func(name, content string, mod time.Time) {
+		t.Helper()
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("a.go", "// TODO: one\n", start)
+	write("b.go", "// FIXME: two\n", start)
+
+	types := []string{"TODO", "FIXME"}
+
+	// search opens the cache, searches and saves it, returning the texts of
+	// the comments found and the cache hits and misses.
+	search := func(types []string) ([]string, int, int) {
+		t.Helper()
+
+		cache, err := todos.OpenCache(cachePath, types, false)
+		if err != nil {
+			t.Fatalf("OpenCache() error = %v", err)
+		}
+
+		comments, err := cache.SearchPaths([]string{src}, nil)
+		if err != nil {
+			t.Fatalf("SearchPaths() error = %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		texts := []string{}
+		for _, c := range comments {
+			texts = append(texts, c.Text)
+		}
+		sort.Strings(texts)
+
+		hits, misses := cache.Stats()
+		return texts, hits, misses
+	}
+
+	tests := []struct {
+		name       string
+		change     func()
+		types      []string
+		wantTexts  []string
+		wantHits   int
+		wantMisses int
+	}{
+		{name: "Empty", wantTexts: []string{"one", "two"}, wantMisses: 2},
+		{name: "Unchanged", wantTexts: []string{"one", "two"}, wantHits: 2},
+		{
+			name:      "Modified",
+			change:    func() { write("a.go", "// TODO: one (edited)\n", start.Add(time.Minute)) },
+			wantTexts: []string{"one (edited)", "two"}, wantHits: 1, wantMisses: 1,
+		},
+		{
+			// A checkout resets modification times without changing content.
+			name:      "Touched",
+			change:    func() { write("b.go", "// FIXME: two\n", start.Add(2*time.Minute)) },
+			wantTexts: []string{"one (edited)", "two"}, wantHits: 2,
+		},
+		{name: "TypesChanged", types: []string{"TODO"}, wantTexts: []string{"one (edited)"}, wantMisses: 2},
+		{
+			name:      "Removed",
+			change:    func() { os.Remove(filepath.Join(src, "a.go")) },
+			types:     []string{"TODO"},
+			wantTexts: []string{}, wantHits: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.change != nil {
+			tt.change()
+		}
+		if tt.types == nil {
+			tt.types = types
+		}
+
+		texts, hits, misses := search(tt.types)
+		if !cmp.Equal(texts, tt.wantTexts) {
+			t.Errorf("%s: comments \n%s", tt.name, cmp.Diff(texts, tt.wantTexts))
+		}
+		if hits != tt.wantHits || misses != tt.wantMisses {
+			t.Errorf("%s: hits, misses = %d, %d, want %d, %d", tt.name, hits, misses, tt.wantHits, tt.wantMisses)
+		}
+	}
+
+	if err := os.WriteFile(cachePath, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, misses := search([]string{"TODO"}); misses != 1 {
+		t.Errorf("corrupt cache misses = %d, want 1", misses)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("// TODO: one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := todos.NewMatcher(todos.MatchOptions{Types: []string{"TODO"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := m.NewCache()
+
+	for i := 0; i < 2; i++ {
+		comments, err := cache.SearchPaths([]string{dir}, nil)
+		if err != nil {
+			t.Fatalf("SearchPaths() error = %v", err)
+		}
+		if len(comments) != 1 || comments[0].Text != "one" {
+			t.Errorf("SearchPaths() = %v", comments)
+		}
+		if err := cache.Save(); err != nil {
+			t.Errorf("Save() error = %v", err)
+		}
+	}
+
+	if hits, misses := cache.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("Stats() = %d, %d, want the second search to hit", hits, misses)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir() = %v, %v, want no cache file", entries, err)
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []todos.SortKey
+		wantErr bool
+	}{
+		{spec: "", want: []todos.SortKey{}},
+		{spec: "author:desc", want: []todos.SortKey{{Field: "author", Desc: true}}},
+		{spec: "type, author:DESC ,file:asc,line", want: []todos.SortKey{{Field: "type"}, {Field: "author", Desc: true}, {Field: "file"}, {Field: "line"}}},
+		{spec: "owner:desc", want: []todos.SortKey{{Field: "owner", Desc: true}}},
+		{spec: "assignee", wantErr: true},
+		{spec: "type:up", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := todos.ParseSortKeys(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSortKeys(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !cmp.Equal(got, tt.want) {
+			t.Errorf("ParseSortKeys(%q) \n%s", tt.spec, cmp.Diff(got, tt.want))
+		}
+	}
+}
+
+func TestSortComments(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "file10.go", Line: 2, Type: "TODO", Author: "bob", Text: "a"},
+		{File: "file2.go", Line: 10, Type: "TODO", Author: "alice", Text: "b"},
+		{File: "file2.go", Line: 9, Type: "FIXME", Author: "bob", Text: "c"},
+		{File: "file2.go", Line: 1, Type: "TODO", Author: "bob", Text: "d"},
+		{File: "file1.go", Line: 5, Type: "FIXME", Author: "alice", Text: "e"},
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		// File and line order is natural: file2 before file10, line 9 before line 10.
+		{name: "Default", spec: "", want: "edcba"},
+		{name: "Line", spec: "line", want: "daecb"},
+		{name: "Desc", spec: "file:desc", want: "adcbe"},
+		{name: "MultiKey", spec: "type,author:desc,file,line", want: "cedab"},
+		// Ties keep file and line order instead of being reversed.
+		{name: "DescTies", spec: "author:desc", want: "dcaeb"},
+	}
+
+	for _, tt := range tests {
+		keys, err := todos.ParseSortKeys(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sorted := append([]todos.Comment{}, comments...)
+		todos.SortComments(sorted, keys)
+
+		got := ""
+		for _, c := range sorted {
+			got += c.Text
+		}
+		if got != tt.want {
+			t.Errorf("%s: SortComments(%q) order = %s, want %s", tt.name, tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "no tags", want: []string{}},
+		{text: "#perf fix #db-pool and #perf again", want: []string{"perf", "db-pool"}},
+		{text: "see #123, a/#path, x#y and &#39;", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := todos.Tags(tt.text); !cmp.Equal(got, tt.want) {
+			t.Errorf("Tags(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGroupComments(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "b/x.go", Line: 1, Type: "TODO", Author: "bob", Text: "one #perf"},
+		{File: "a/y.go", Line: 2, Type: "FIXME", Text: "two #perf #db"},
+		{File: "b/z.go", Line: 3, Type: "TODO", Author: "alice", Text: "three"},
+		{File: "a/y.go", Line: 4, Type: "TODO", Author: "bob", Text: "four"},
+	}
+
+	groups := todos.GroupComments(comments, []string{"author", "type"}, []todos.SortKey{{Field: "author", Desc: true}})
+	want := []todos.Group{
+		{Field: "author", Name: "bob", Count: 2, Groups: []todos.Group{
+			{Field: "type", Name: "TODO", Count: 2, Comments: []todos.Comment{comments[0], comments[3]}},
+		}},
+		{Field: "author", Name: "alice", Count: 1, Groups: []todos.Group{
+			{Field: "type", Name: "TODO", Count: 1, Comments: []todos.Comment{comments[2]}},
+		}},
+		{Field: "author", Count: 1, Groups: []todos.Group{
+			{Field: "type", Name: "FIXME", Count: 1, Comments: []todos.Comment{comments[1]}},
+		}},
+	}
+	if !cmp.Equal(groups, want) {
+		t.Errorf("GroupComments(author, type) \n%s", cmp.Diff(groups, want))
+	}
+
+	names := func(groups []todos.Group) []string {
+		result := []string{}
+		for _, g := range groups {
+			result = append(result, fmt.Sprintf("%s=%d", g.Label(), g.Count))
+		}
+		return result
+	}
+
+	if got, want := names(todos.GroupComments(comments, []string{"dir"}, nil)), []string{"a=2", "b=2"}; !cmp.Equal(got, want) {
+		t.Errorf("GroupComments(dir) = %v, want %v", got, want)
+	}
+	if got, want := names(todos.GroupComments(comments, []string{"tag"}, nil)), []string{"db=1", "perf=2", "(no tag)=2"}; !cmp.Equal(got, want) {
+		t.Errorf("GroupComments(tag) = %v, want %v", got, want)
+	}
+
+	owned := []todos.Comment{
+		{File: "a.go", Owners: []string{"@web", "@core"}},
+		{File: "b.go", Owners: []string{"@core"}},
+		{File: "c.go"},
+	}
+	if got, want := names(todos.GroupComments(owned, []string{"owner"}, nil)), []string{"@core=2", "@web=1", "(no owner)=1"}; !cmp.Equal(got, want) {
+		t.Errorf("GroupComments(owner) = %v, want %v", got, want)
+	}
+
+	if _, err := todos.ParseGroupBy("author,assignee"); err == nil {
+		t.Error("ParseGroupBy(author,assignee) error = nil")
+	}
+}
+
+func TestWriteFileGroup(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "file10.go", Line: 3, Type: "TODO", Text: "c"},
+		{File: "file2.go", Line: 10, Type: "FIXME", Author: "bob", Text: "b"},
+		{File: "file2.go", Line: 9, Type: "TODO", Text: "a"},
+	}
+
+	want := "file2.go [2 Comments]:\n" +
+		"9   |  TODO:        a  \n" +
+		"10  |  FIXME(bob):  b  \n" +
+		"\n" +
+		"file10.go [1 Comments]:\n" +
+		"3  |  TODO:  c  \n" +
+		"\n"
+
+	// The output must not depend on map iteration order.
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := todos.WriteFileGroup(&buf, append([]todos.Comment{}, comments...), nil, nil); err != nil {
+			t.Fatalf("WriteFileGroup() error = %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Fatalf("WriteFileGroup() \n%s", cmp.Diff(got, want))
+		}
+	}
+}
+
+func TestAssignSeverities(t *testing.T) {
+	comments := []todos.Comment{
+		{Type: "TODO"},
+		{Type: "FIXME"},
+		{Type: "BUG"},
+		{Type: "OPTIMIZE"},
+		{Type: "todo", Severity: todos.SeverityError},
+	}
+
+	todos.AssignSeverities(comments, map[string]string{"fixme": todos.SeverityError})
+
+	got := []string{}
+	for _, c := range comments {
+		got = append(got, c.Severity)
+	}
+	want := []string{"info", "error", "error", "warning", "info"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("AssignSeverities() \n%s", cmp.Diff(got, want))
+	}
+
+	if severity, err := todos.ParseSeverity(" Warning "); err != nil || severity != todos.SeverityWarning {
+		t.Errorf("ParseSeverity(Warning) = %q, %v", severity, err)
+	}
+	if _, err := todos.ParseSeverity("critical"); err == nil {
+		t.Error("ParseSeverity(critical) error = nil")
+	}
+}
+
+func TestWriteCI(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "./cmd/main.go", Line: 3, Type: "TODO", Text: "flags, 100% done", Severity: todos.SeverityInfo},
+		{File: "db.go", Line: 10, Type: "BUG", Author: "alice", Authors: []string{"alice", "bob"}, Text: "leaks <conns>", Severity: todos.SeverityError},
+	}
+
+	t.Run("GitHub", func(t *testing.T) {
+		want := "::notice file=cmd/main.go,line=3,title=TODO::TODO: flags, 100%25 done\n" +
+			"::error file=db.go,line=10,title=BUG::BUG(alice, bob): leaks <conns>\n"
+
+		var buf bytes.Buffer
+		if err := todos.WriteGitHub(&buf, append([]todos.Comment{}, comments...), nil); err != nil {
+			t.Fatalf("WriteGitHub() error = %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("WriteGitHub() \n%s", cmp.Diff(got, want))
+		}
+	})
+
+	t.Run("Checkstyle", func(t *testing.T) {
+		want := `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="./cmd/main.go">
+    <error line="3" severity="info" message="TODO: flags, 100% done" source="todos.TODO"></error>
+  </file>
+  <file name="db.go">
+    <error line="10" severity="error" message="BUG(alice, bob): leaks &lt;conns&gt;" source="todos.BUG"></error>
+  </file>
+</checkstyle>
+`
+
+		var buf bytes.Buffer
+		if err := todos.WriteCheckstyle(&buf, append([]todos.Comment{}, comments...), nil); err != nil {
+			t.Fatalf("WriteCheckstyle() error = %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("WriteCheckstyle() \n%s", cmp.Diff(got, want))
+		}
+	})
+
+	t.Run("SARIF", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := todos.WriteSARIF(&buf, append([]todos.Comment{}, comments...), nil); err != nil {
+			t.Fatalf("WriteSARIF() error = %v", err)
+		}
+
+		var log struct {
+			Version string
+			Runs    []struct {
+				Tool struct {
+					Driver struct {
+						Rules []struct {
+							ID string
+						}
+					}
+				}
+				Results []struct {
+					RuleID    string
+					Level     string
+					Message   struct{ Text string }
+					Locations []struct {
+						PhysicalLocation struct {
+							ArtifactLocation struct{ URI string }
+							Region           struct{ StartLine int }
+						}
+					}
+				}
+			}
+		}
+		if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+			t.Fatalf("WriteSARIF() wrote invalid JSON: %v", err)
+		}
+		if log.Version != "2.1.0" || len(log.Runs) != 1 {
+			t.Fatalf("WriteSARIF() version = %q, runs = %d", log.Version, len(log.Runs))
+		}
+
+		run := log.Runs[0]
+		got := []string{}
+		for _, rule := range run.Tool.Driver.Rules {
+			got = append(got, rule.ID)
+		}
+		for _, r := range run.Results {
+			location := r.Locations[0].PhysicalLocation
+			got = append(got, fmt.Sprintf("%s %s %s:%d %s", r.RuleID, r.Level, location.ArtifactLocation.URI, location.Region.StartLine, r.Message.Text))
+		}
+
+		want := []string{
+			"TODO",
+			"BUG",
+			"TODO note cmd/main.go:3 TODO: flags, 100% done",
+			"BUG error db.go:10 BUG(alice, bob): leaks <conns>",
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("WriteSARIF() \n%s", cmp.Diff(got, want))
+		}
+	})
+}
+
+func TestWriteColorTable(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "a.go", Line: 1, Type: "TODO", Text: "info", Severity: todos.SeverityInfo},
+		{File: "a.go", Line: 2, Type: "BUG", Text: "error", Severity: todos.SeverityError},
+	}
+
+	var plain, colored bytes.Buffer
+	if err := todos.WriteTable(&plain, append([]todos.Comment{}, comments...), nil, nil); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	if err := todos.WriteColorTable(&colored, append([]todos.Comment{}, comments...), nil, nil); err != nil {
+		t.Fatalf("WriteColorTable() error = %v", err)
+	}
+
+	if !strings.Contains(colored.String(), "\033[31mBUG\033[0m") {
+		t.Errorf("WriteColorTable() does not color BUG red:\n%q", colored.String())
+	}
+
+	// Without the color codes the columns must line up as in the plain table.
+	got := regexp.MustCompile("\033\\[[0-9;]*m").ReplaceAllString(colored.String(), "")
+	if want := plain.String(); got != want {
+		t.Errorf("WriteColorTable() without colors \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestIgnoredPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		ignores []string
+		want    bool
+	}{
+		{path: "main.go", ignores: nil, want: false},
+		{path: "vendor/lib/x.go", ignores: []string{"vendor/"}, want: true},
+		{path: "a/node_modules/x.js", ignores: []string{"node_modules"}, want: true},
+		{path: "web/app.min.js", ignores: []string{"*.min.js"}, want: true},
+		{path: "internal/gen/x.go", ignores: []string{"internal/gen"}, want: true},
+		{path: "pkg/internal/gen/x.go", ignores: []string{"internal/gen"}, want: false},
+		{path: "pkg/internal/gen/x.go", ignores: []string{"**/internal/gen"}, want: true},
+		{path: "vendor/x.go", ignores: []string{"!vendor/", "# vendor"}, want: false},
+		{path: ".github/ci.yml", ignores: []string{".*"}, want: true},
+		{path: ".github/ci.yml", ignores: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := todos.IgnoredPath(tt.path, tt.ignores); got != tt.want {
+			t.Errorf("IgnoredPath(%q, %q) = %v, want %v", tt.path, tt.ignores, got, tt.want)
+		}
+	}
+}
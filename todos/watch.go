@@ -0,0 +1,183 @@
+package todos
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+)
+
+// fileState is the state of a file used to detect modifications.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watcher keeps the comments in a set of paths up to date by polling the
+// files for changes. Only files that were added or modified since the
+// previous scan are parsed again.
+type Watcher struct {
+	paths   []string
+	ignores []string
+	matcher *Matcher
+
+	files    map[string]fileState
+	comments map[string][]Comment
+}
+
+// NewWatcher returns a watcher for the comments in paths. The arguments are
+// the same as those of SearchPaths.
+func NewWatcher(paths []string, commentTypes []string, ignores []string, permissive bool) *Watcher {
+	// The matcher can't be invalid without patterns.
+	m, _ := NewMatcher(MatchOptions{Types: commentTypes, Permissive: permissive})
+	return m.NewWatcher(paths, ignores)
+}
+
+// NewWatcher returns a watcher for the comments found by the matcher in
+// paths.
+func (m *Matcher) NewWatcher(paths []string, ignores []string) *Watcher {
+	return &Watcher{
+		paths:    paths,
+		ignores:  ignores,
+		matcher:  m,
+		files:    map[string]fileState{},
+		comments: map[string][]Comment{},
+	}
+}
+
+// Scan checks the files for changes and returns the comments added and
+// removed since the previous scan. The first scan returns every comment as
+// added. Comments that only moved to another line are not reported.
+func (w *Watcher) Scan() (added []Comment, removed []Comment, err error) {
+	files, err := Files(w.paths, w.ignores)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, path := range files {
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if old, ok := w.files[path]; ok && old.size == state.size && old.modTime.Equal(state.modTime) {
+			continue
+		}
+
+		comments, err := w.parse(path)
+		if err != nil {
+			continue
+		}
+
+		a, r := diffComments(w.comments[path], comments)
+		added = append(added, a...)
+		removed = append(removed, r...)
+
+		w.files[path] = state
+		w.comments[path] = comments
+	}
+
+	for path := range w.files {
+		if seen[path] {
+			continue
+		}
+		removed = append(removed, w.comments[path]...)
+		delete(w.files, path)
+		delete(w.comments, path)
+	}
+
+	sortByFileLine(added)
+	sortByFileLine(removed)
+
+	return added, removed, nil
+}
+
+// Comments returns the comments found by the last scan, ordered by file and line.
+func (w *Watcher) Comments() []Comment {
+	comments := []Comment{}
+	for _, fileComments := range w.comments {
+		comments = append(comments, fileComments...)
+	}
+	sortByFileLine(comments)
+	return comments
+}
+
+// Watch scans every interval until ctx is done, calling fn after the first
+// scan and after every scan that found changes.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, fn func(added, removed []Comment)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	first := true
+	for {
+		added, removed, err := w.Scan()
+		if err != nil {
+			return err
+		}
+
+		if first || len(added) > 0 || len(removed) > 0 {
+			fn(added, removed)
+			first = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) parse(path string) ([]Comment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return w.matcher.Parse(file, path)
+}
+
+// diffComments returns the comments in next that are not in prev and the
+// comments in prev that are not in next, ignoring line numbers.
+func diffComments(prev, next []Comment) (added []Comment, removed []Comment) {
+	type key struct{ typ, author, text string }
+
+	counts := map[key]int{}
+	for _, c := range prev {
+		counts[key{c.Type, c.Author, c.Text}]++
+	}
+
+	for _, c := range next {
+		k := key{c.Type, c.Author, c.Text}
+		if counts[k] > 0 {
+			counts[k]--
+			continue
+		}
+		added = append(added, c)
+	}
+
+	for _, c := range prev {
+		k := key{c.Type, c.Author, c.Text}
+		if counts[k] > 0 {
+			counts[k]--
+			removed = append(removed, c)
+		}
+	}
+
+	return added, removed
+}
+
+// sortByFileLine sorts comments by file and line.
+func sortByFileLine(comments []Comment) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		if comments[i].File == comments[j].File {
+			return comments[i].Line < comments[j].Line
+		}
+		return comments[i].File < comments[j].File
+	})
+}
@@ -0,0 +1,176 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// File is a tracker that keeps issues in a local JSON file. It is meant for
+// trying out sync and for tests.
+type File struct {
+	path string
+}
+
+// fileData is the content of the JSON file.
+type fileData struct {
+	Next   int     `json:"next"`
+	Issues []Issue `json:"issues"`
+}
+
+// NewFile returns a tracker that keeps issues in the JSON file at path. The
+// file is created by the first change.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) load() (*fileData, error) {
+	data := &fileData{Next: 1, Issues: []Issue{}}
+
+	content, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return data, nil
+}
+
+func (f *File) save(data *fileData) error {
+	var content bytes.Buffer
+	enc := json.NewEncoder(&content)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".todos-issues-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// index returns the position of the issue with the given ID.
+func (d *fileData) index(id string) (int, error) {
+	for i, issue := range d.Issues {
+		if issue.ID == id {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", errNotFound, id)
+}
+
+// Create creates an open issue with the next number as its ID.
+func (f *File) Create(issue Issue) (Issue, error) {
+	data, err := f.load()
+	if err != nil {
+		return Issue{}, err
+	}
+
+	issue.ID = "#" + strconv.Itoa(data.Next)
+	issue.State = Open
+	data.Next++
+	data.Issues = append(data.Issues, issue)
+
+	return issue, f.save(data)
+}
+
+// Update replaces the title and body of an issue.
+func (f *File) Update(issue Issue) (Issue, error) {
+	data, err := f.load()
+	if err != nil {
+		return Issue{}, err
+	}
+
+	i, err := data.index(issue.ID)
+	if err != nil {
+		return Issue{}, err
+	}
+
+	data.Issues[i].Title = issue.Title
+	data.Issues[i].Body = issue.Body
+
+	return data.Issues[i], f.save(data)
+}
+
+// Close closes an issue.
+func (f *File) Close(id string) error {
+	data, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	i, err := data.index(id)
+	if err != nil {
+		return err
+	}
+
+	data.Issues[i].State = Closed
+	return f.save(data)
+}
+
+// Find returns the issue with the queried ID, or the open issues with all
+// of the queried labels.
+func (f *File) Find(query Query) ([]Issue, error) {
+	if query.ID != "" {
+		if _, err := number(query.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Issue{}
+	for _, issue := range data.Issues {
+		if query.ID != "" {
+			if issue.ID == query.ID {
+				result = append(result, issue)
+			}
+			continue
+		}
+
+		if issue.State == Open && hasLabels(issue, query.Labels) {
+			result = append(result, issue)
+		}
+	}
+
+	return result, nil
+}
+
+func hasLabels(issue Issue, labels []string) bool {
+	for _, label := range labels {
+		found := false
+		for _, l := range issue.Labels {
+			found = found || l == label
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,223 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubURL is the base URL of the GitHub REST API.
+const DefaultGitHubURL = "https://api.github.com"
+
+// githubPageSize is the number of issues requested per page.
+const githubPageSize = 100
+
+var (
+	errGitHub    = errors.New("github request failed")
+	errForeignID = errors.New("not a reference to an issue of this tracker")
+)
+
+// GitHub is a tracker for the issues of a GitHub repository, using the REST API.
+type GitHub struct {
+	baseURL string
+	repo    string
+	token   string
+	client  *http.Client
+}
+
+// NewGitHub returns a tracker for the issues of repo (owner/name). The API
+// is reached at baseURL, or DefaultGitHubURL if it is empty, so a GitHub
+// Enterprise server or a test server can be used. The token may be empty for
+// read-only access to public repositories.
+func NewGitHub(baseURL string, repo string, token string) *GitHub {
+	if baseURL == "" {
+		baseURL = DefaultGitHubURL
+	}
+
+	return &GitHub{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		repo:    repo,
+		token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// githubIssue is an issue as returned by the API.
+type githubIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+func (i githubIssue) issue() Issue {
+	issue := Issue{
+		ID:     "#" + strconv.Itoa(i.Number),
+		Title:  i.Title,
+		Body:   i.Body,
+		State:  i.State,
+		Labels: []string{},
+		URL:    i.HTMLURL,
+	}
+	for _, label := range i.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+	return issue
+}
+
+// number returns the issue number of a #123 reference.
+func number(id string) (string, error) {
+	n := strings.TrimPrefix(id, "#")
+	if _, err := strconv.Atoi(n); err != nil || n == id {
+		return "", fmt.Errorf("%w: %s", errForeignID, id)
+	}
+	return n, nil
+}
+
+// do sends a request to the API and decodes the JSON response into out. It
+// reports whether the resource was found.
+func (g *GitHub) do(method string, path string, body interface{}, out interface{}) (bool, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, g.baseURL+path, r)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return false, nil
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return false, fmt.Errorf("%w: %s %s: %s: %s", errGitHub, method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return true, nil
+	}
+	return true, json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (g *GitHub) issuesPath() string {
+	return "/repos/" + g.repo + "/issues"
+}
+
+// Create opens an issue.
+func (g *GitHub) Create(issue Issue) (Issue, error) {
+	body := map[string]interface{}{"title": issue.Title, "body": issue.Body}
+	if len(issue.Labels) > 0 {
+		body["labels"] = issue.Labels
+	}
+
+	var created githubIssue
+	if _, err := g.do(http.MethodPost, g.issuesPath(), body, &created); err != nil {
+		return Issue{}, err
+	}
+	return created.issue(), nil
+}
+
+// Update replaces the title and body of an issue.
+func (g *GitHub) Update(issue Issue) (Issue, error) {
+	return g.patch(issue.ID, map[string]interface{}{"title": issue.Title, "body": issue.Body})
+}
+
+// Close closes an issue.
+func (g *GitHub) Close(id string) error {
+	_, err := g.patch(id, map[string]interface{}{"state": Closed})
+	return err
+}
+
+func (g *GitHub) patch(id string, body map[string]interface{}) (Issue, error) {
+	n, err := number(id)
+	if err != nil {
+		return Issue{}, err
+	}
+
+	var updated githubIssue
+	found, err := g.do(http.MethodPatch, g.issuesPath()+"/"+n, body, &updated)
+	if err != nil {
+		return Issue{}, err
+	}
+	if !found {
+		return Issue{}, fmt.Errorf("%w: %s", errNotFound, id)
+	}
+	return updated.issue(), nil
+}
+
+// Find returns the issue or pull request with the queried ID, or the open
+// issues with all of the queried labels, leaving out pull requests.
+func (g *GitHub) Find(query Query) ([]Issue, error) {
+	if query.ID != "" {
+		n, err := number(query.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var issue githubIssue
+		found, err := g.do(http.MethodGet, g.issuesPath()+"/"+n, nil, &issue)
+		if err != nil || !found {
+			return []Issue{}, err
+		}
+		return []Issue{issue.issue()}, nil
+	}
+
+	params := url.Values{}
+	params.Set("state", Open)
+	params.Set("per_page", strconv.Itoa(githubPageSize))
+	if len(query.Labels) > 0 {
+		params.Set("labels", strings.Join(query.Labels, ","))
+	}
+
+	result := []Issue{}
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+
+		var issues []githubIssue
+		if _, err := g.do(http.MethodGet, g.issuesPath()+"?"+params.Encode(), nil, &issues); err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			if issue.PullRequest == nil {
+				result = append(result, issue.issue())
+			}
+		}
+
+		if len(issues) < githubPageSize {
+			return result, nil
+		}
+	}
+}
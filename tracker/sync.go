@@ -0,0 +1,334 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/euforic/todos/diff"
+	"github.com/euforic/todos/todos"
+)
+
+// Kinds of sync actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionLink   = "link"
+	ActionClose  = "close"
+)
+
+// maxTitle is the maximum length of an issue title, in characters.
+const maxTitle = 80
+
+var errWriteBack = errors.New("can't add the issue reference to the comment")
+
+// markerRegex matches the marker that sync adds to the body of its issues to
+// find the comment an issue was created for: the fingerprint of the comment
+// and the path of its file relative to the sync root.
+var markerRegex = regexp.MustCompile(`<!-- todos:fingerprint=([0-9a-f]+)(?: path=(.+?))? -->`)
+
+// closers are the comment closing delimiters that the issue reference is
+// inserted before.
+var closers = []string{"*/", "-->", "--}}", "-}", "#}", "%}"}
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// Label marks the issues that sync manages. Defaults to DefaultLabel.
+	Label string
+	// Close closes managed issues that no comment refers to anymore.
+	Close bool
+	// Paths are the files and directories the comments were found in. If
+	// set, only managed issues whose comment was in one of them are closed.
+	// Issues of comments elsewhere are left alone, since their comments
+	// weren't searched.
+	Paths []string
+	// Root is the directory the file paths recorded in issues are relative
+	// to, such as the repository root, so issues are found the same way from
+	// any working directory. Defaults to the working directory.
+	Root string
+	// DryRun reports the actions without changing the tracker or files.
+	DryRun bool
+}
+
+// Action is a change made by Sync. Comment is nil for closed issues.
+type Action struct {
+	Kind    string         `json:"kind"`
+	Issue   Issue          `json:"issue"`
+	Comment *todos.Comment `json:"comment,omitempty"`
+}
+
+// Sync makes the tracker reflect the comments. An issue is created for every
+// comment without an issue reference and its ID is added to the end of the
+// comment in the source file. Comments that refer to an issue managed by
+// sync update it when their text changed, and managed issues that no
+// comment refers to are closed if opts.Close is set. Managed issues carry
+// the label and a marker with the fingerprint of their comment, so a comment
+// whose issue was created but not written back is linked to it on the next
+// run instead of getting a duplicate.
+//
+// The comments should be all the comments of the project or of opts.Paths,
+// or managed issues of the comments left out are closed.
+func Sync(t Tracker, comments []todos.Comment, opts SyncOptions) ([]Action, error) {
+	if opts.Label == "" {
+		opts.Label = DefaultLabel
+	}
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	managed, err := t.Find(Query{Labels: []string{opts.Label}})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string]Issue{}
+	byFingerprint := map[string]Issue{}
+	for _, issue := range managed {
+		byID[issue.ID] = issue
+		if m := markerRegex.FindStringSubmatch(issue.Body); m != nil {
+			byFingerprint[m[1]] = issue
+		}
+	}
+
+	actions := []Action{}
+	referenced := map[string]bool{}
+	links := map[string][]link{}
+
+	for i := range comments {
+		comment := comments[i]
+
+		// The issue reference and comment closer are left out so writing
+		// back the reference doesn't change the fingerprint.
+		clean := comment
+		clean.Text = syncText(comment)
+		fingerprint := diff.Fingerprint(clean)
+		path := relPath(root, comment.File)
+
+		if comment.Issue != "" {
+			referenced[comment.Issue] = true
+
+			issue, ok := byID[comment.Issue]
+			if !ok || fingerprintOf(issue) == fingerprint && pathOf(issue) == path {
+				continue
+			}
+
+			issue.Title, issue.Body = title(clean), body(clean, fingerprint, path)
+			if !opts.DryRun {
+				if issue, err = t.Update(issue); err != nil {
+					return actions, err
+				}
+			}
+			actions = append(actions, Action{Kind: ActionUpdate, Issue: issue, Comment: &comment})
+			continue
+		}
+
+		kind := ActionLink
+		issue, ok := byFingerprint[fingerprint]
+		if !ok {
+			kind = ActionCreate
+			issue = Issue{Title: title(clean), Body: body(clean, fingerprint, path), State: Open, Labels: []string{opts.Label}}
+			if !opts.DryRun {
+				if issue, err = t.Create(issue); err != nil {
+					return actions, err
+				}
+			}
+			byFingerprint[fingerprint] = issue
+		}
+
+		referenced[issue.ID] = true
+		links[comment.File] = append(links[comment.File], link{line: comment.Line, text: comment.Text, id: issue.ID})
+		actions = append(actions, Action{Kind: kind, Issue: issue, Comment: &comment})
+	}
+
+	if !opts.DryRun {
+		files := make([]string, 0, len(links))
+		for file := range links {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			if err := writeLinks(file, links[file]); err != nil {
+				return actions, err
+			}
+		}
+	}
+
+	if opts.Close {
+		for _, issue := range managed {
+			if referenced[issue.ID] || !inPaths(issue, root, opts.Paths) {
+				continue
+			}
+			if !opts.DryRun {
+				if err := t.Close(issue.ID); err != nil {
+					return actions, err
+				}
+			}
+			issue.State = Closed
+			actions = append(actions, Action{Kind: ActionClose, Issue: issue})
+		}
+	}
+
+	return actions, nil
+}
+
+// inPaths reports whether the comment an issue was created for is in one of
+// the paths, or whether paths is empty. Issues that don't record the file of
+// their comment are in none of the paths.
+func inPaths(issue Issue, root string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+
+	path := pathOf(issue)
+	if path == "" {
+		return false
+	}
+	file := filepath.Join(root, filepath.FromSlash(path))
+
+	for _, p := range paths {
+		dir, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		if file == dir || strings.HasPrefix(file, dir+string(filepath.Separator)) || dir == filepath.Dir(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// relPath returns the path of file relative to root with forward slashes,
+// or file itself if it is not below root.
+func relPath(root, file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func fingerprintOf(issue Issue) string {
+	if m := markerRegex.FindStringSubmatch(issue.Body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// pathOf returns the path recorded in the marker of an issue, or an empty
+// string if there is none.
+func pathOf(issue Issue) string {
+	if m := markerRegex.FindStringSubmatch(issue.Body); m != nil {
+		return m[2]
+	}
+	return ""
+}
+
+// syncText returns the text of a comment without a trailing comment closer
+// or the reference to its issue.
+func syncText(c todos.Comment) string {
+	text := c.Text
+	for _, closer := range closers {
+		if strings.HasSuffix(text, closer) {
+			text = strings.TrimSuffix(text, closer)
+			break
+		}
+	}
+
+	if c.Issue != "" {
+		if stripped := strings.Replace(text, "("+c.Issue+")", "", 1); stripped != text {
+			text = stripped
+		} else {
+			text = strings.Replace(text, c.Issue, "", 1)
+		}
+	}
+
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// title returns the issue title for a comment, shortened to maxTitle characters.
+func title(c todos.Comment) string {
+	t := c.Type + ": " + c.Text
+	if utf8.RuneCountInString(t) > maxTitle {
+		t = string([]rune(t)[:maxTitle-1]) + "…"
+	}
+	return t
+}
+
+// body returns the issue body for a comment in the file at path, relative
+// to the sync root, with its marker.
+func body(c todos.Comment, fingerprint, path string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", c.Text)
+	fmt.Fprintf(&b, "Found in `%s:%d`", path, c.Line)
+	if c.Author != "" {
+		fmt.Fprintf(&b, ", assigned to %s", c.Author)
+	}
+	fmt.Fprintf(&b, ".\n\n<!-- todos:fingerprint=%s path=%s -->\n", fingerprint, path)
+	return b.String()
+}
+
+// link is an issue reference to add to the comment on a line.
+type link struct {
+	line int
+	text string
+	id   string
+}
+
+// writeLinks adds the issue references to the comments in a file.
+func writeLinks(path string, links []link) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	for _, l := range links {
+		if l.line < 1 || l.line > len(lines) {
+			return fmt.Errorf("%w: %s:%d is past the end of the file", errWriteBack, path, l.line)
+		}
+
+		line, ok := addReference(lines[l.line-1], l.text, l.id)
+		if !ok {
+			return fmt.Errorf("%w: %s:%d no longer has the comment", errWriteBack, path, l.line)
+		}
+		lines[l.line-1] = line
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "")), info.Mode().Perm())
+}
+
+// addReference adds " (id)" after the comment text on a line, before any
+// comment closing delimiter.
+func addReference(line string, text string, id string) (string, bool) {
+	end := len(strings.TrimRight(line, "\r\n"))
+
+	start := strings.LastIndex(line[:end], text)
+	if start < 0 {
+		return line, false
+	}
+
+	at := start + len(text)
+	for _, closer := range closers {
+		if strings.HasSuffix(text, closer) {
+			at = start + len(strings.TrimRight(strings.TrimSuffix(text, closer), " \t"))
+			break
+		}
+	}
+
+	return line[:at] + " (" + id + ")" + line[at:], true
+}
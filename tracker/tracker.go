@@ -0,0 +1,116 @@
+// Package tracker creates, updates and closes issues in an issue tracker
+// for the comments found in source code.
+package tracker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// States of an issue. Missing is returned by State for issues that do not exist.
+const (
+	Open    = "open"
+	Closed  = "closed"
+	Missing = "missing"
+)
+
+var (
+	errUnknownTracker = errors.New("unknown tracker")
+	errNotFound       = errors.New("issue not found")
+	errConfig         = errors.New("invalid tracker config")
+)
+
+// Issue is an issue in a tracker.
+type Issue struct {
+	// ID is the reference to the issue as written in comments, e.g. #123.
+	ID     string   `json:"id"`
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	State  string   `json:"state"`
+	Labels []string `json:"labels,omitempty"`
+	URL    string   `json:"url,omitempty"`
+}
+
+// Query selects the issues returned by Find. If ID is set only that issue
+// is returned, whatever its state. Otherwise the open issues that have all
+// of Labels are returned.
+type Query struct {
+	ID     string
+	Labels []string
+}
+
+// Tracker is an issue tracker.
+type Tracker interface {
+	// Create creates an open issue and returns it with its ID set.
+	Create(issue Issue) (Issue, error)
+	// Update replaces the title and body of the issue with the same ID.
+	Update(issue Issue) (Issue, error)
+	// Close closes the issue with the given ID.
+	Close(id string) error
+	// Find returns the issues selected by the query. No issues and no
+	// error are returned if the issue with the queried ID does not exist.
+	Find(query Query) ([]Issue, error)
+}
+
+// State returns the state of the issue with the given ID: Open, Closed or
+// Missing. An empty state is returned if the ID does not refer to an issue
+// of the tracker, such as PROJ-45 for a GitHub tracker.
+func State(t Tracker, id string) (string, error) {
+	issues, err := t.Find(Query{ID: id})
+	if errors.Is(err, errForeignID) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(issues) == 0 {
+		return Missing, nil
+	}
+	return issues[0].State, nil
+}
+
+// Config selects and configures a tracker.
+type Config struct {
+	// Type is the kind of tracker: github or file.
+	Type string `json:"type"`
+	// Repo is the owner/name of a GitHub repository.
+	Repo string `json:"repo,omitempty"`
+	// URL is the base URL of the GitHub API. Defaults to DefaultGitHubURL.
+	URL string `json:"url,omitempty"`
+	// TokenEnv is the environment variable holding the GitHub token.
+	// Defaults to GITHUB_TOKEN.
+	TokenEnv string `json:"token_env,omitempty"`
+	// Path is the JSON file of a file tracker.
+	Path string `json:"path,omitempty"`
+	// Label marks the issues created by sync. Defaults to DefaultLabel.
+	Label string `json:"label,omitempty"`
+}
+
+// DefaultLabel is the label of the issues created by sync when none is configured.
+const DefaultLabel = "todo"
+
+// New returns the tracker described by cfg.
+func New(cfg Config) (Tracker, error) {
+	switch cfg.Type {
+	case "github":
+		if cfg.Repo == "" {
+			return nil, fmt.Errorf("%w: github tracker needs a repo (owner/name)", errConfig)
+		}
+		tokenEnv := cfg.TokenEnv
+		if tokenEnv == "" {
+			tokenEnv = "GITHUB_TOKEN"
+		}
+		return NewGitHub(cfg.URL, cfg.Repo, os.Getenv(tokenEnv)), nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("%w: file tracker needs a path", errConfig)
+		}
+		return NewFile(cfg.Path), nil
+	case "":
+		return nil, fmt.Errorf("%w: no tracker configured", errConfig)
+	default:
+		return nil, fmt.Errorf("%w: %q (github, file)", errUnknownTracker, cfg.Type)
+	}
+}
@@ -0,0 +1,354 @@
+package tracker
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/euforic/todos/diff"
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFile(t *testing.T) {
+	f := NewFile(filepath.Join(t.TempDir(), "issues.json"))
+
+	a, err := f.Create(Issue{Title: "a", Labels: []string{"todo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.Create(Issue{Title: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != "#1" || b.ID != "#2" || a.State != Open {
+		t.Fatalf("Create() = %+v, %+v", a, b)
+	}
+
+	a.Title = "a2"
+	if _, err := f.Update(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close("#9"); err == nil {
+		t.Error("Close(#9) error = nil")
+	}
+
+	tests := []struct {
+		query Query
+		want  []string
+	}{
+		{query: Query{}, want: []string{"#1 a2 open"}},
+		{query: Query{Labels: []string{"todo"}}, want: []string{"#1 a2 open"}},
+		{query: Query{Labels: []string{"other"}}, want: []string{}},
+		{query: Query{ID: "#2"}, want: []string{"#2 b closed"}},
+		{query: Query{ID: "#3"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		issues, err := f.Find(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, issue := range issues {
+			got = append(got, issue.ID+" "+issue.Title+" "+issue.State)
+		}
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("Find(%+v) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestGitHub(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+strings.TrimSpace(string(body)))
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"number": 5, "title": "new", "state": "open", "html_url": "https://example.com/5", "labels": [{"name": "todo"}]}`)
+		case r.URL.Path == "/repos/acme/app/issues/404":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/repos/acme/app/issues/5":
+			io.WriteString(w, `{"number": 5, "title": "new", "state": "closed"}`)
+		case r.URL.Path == "/repos/acme/app/issues":
+			io.WriteString(w, `[{"number": 1, "title": "one", "state": "open"}, {"number": 2, "title": "pr", "state": "open", "pull_request": {}}]`)
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer server.Close()
+
+	g := NewGitHub(server.URL+"/", "acme/app", "secret")
+
+	created, err := g.Create(Issue{Title: "new", Body: "text", Labels: []string{"todo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Issue{ID: "#5", Title: "new", State: Open, Labels: []string{"todo"}, URL: "https://example.com/5"}
+	if !cmp.Equal(created, want) {
+		t.Errorf("Create() \n%s", cmp.Diff(created, want))
+	}
+
+	if err := g.Close("#5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close("PROJ-5"); err == nil {
+		t.Error("Close(PROJ-5) error = nil")
+	}
+
+	found, err := g.Find(Query{ID: "#404"})
+	if err != nil || len(found) != 0 {
+		t.Errorf("Find(#404) = %v, %v", found, err)
+	}
+
+	found, err = g.Find(Query{Labels: []string{"todo", "bug"}})
+	if err != nil || len(found) != 1 || found[0].ID != "#1" {
+		t.Errorf("Find(labels) = %v, %v", found, err)
+	}
+
+	wantRequests := []string{
+		`POST /repos/acme/app/issues {"body":"text","labels":["todo"],"title":"new"}`,
+		`PATCH /repos/acme/app/issues/5 {"state":"closed"}`,
+		`GET /repos/acme/app/issues/404 `,
+		`GET /repos/acme/app/issues?labels=todo%2Cbug&page=1&per_page=100&state=open `,
+	}
+	if !cmp.Equal(requests, wantRequests) {
+		t.Errorf("requests \n%s", cmp.Diff(requests, wantRequests))
+	}
+
+	if _, err := NewGitHub(server.URL, "acme/app", "").Find(Query{}); err == nil {
+		t.Error("Find() without a token error = nil")
+	}
+}
+
+func TestAddReference(t *testing.T) {
+	tests := []struct {
+		line string
+		text string
+		want string
+		ok   bool
+	}{
+		{line: "// TODO: fix it\n", text: "fix it", want: "// TODO: fix it (#1)\n", ok: true},
+		{line: "\t/* TODO: fix it */\r\n", text: "fix it */", want: "\t/* TODO: fix it (#1) */\r\n", ok: true},
+		{line: "<!-- TODO: fix it -->", text: "fix it -->", want: "<!-- TODO: fix it (#1) -->", ok: true},
+		{line: "x := 1 // TODO:", text: "", want: "x := 1 // TODO: (#1)", ok: true},
+		{line: "// something else\n", text: "fix it", want: "// something else\n", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := addReference(tt.line, tt.text, "#1")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("addReference(%q, %q) = %q, %v, want %q, %v", tt.line, tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "a.go")
+	f := NewFile(filepath.Join(dir, "issues.json"))
+
+	scan := func(content string) []todos.Comment {
+		t.Helper()
+		if content != "" {
+			if err := os.WriteFile(source, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		comments, err := todos.Search(dir, []string{"TODO", "FIXME"}, []string{"issues.json"}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return comments
+	}
+
+	sync := func(comments []todos.Comment, dryRun bool) []string {
+		t.Helper()
+		actions, err := Sync(f, comments, SyncOptions{Close: true, Root: dir, DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, a := range actions {
+			got = append(got, a.Kind+" "+a.Issue.ID+" "+a.Issue.Title)
+		}
+		return got
+	}
+
+	comments := scan("// TODO: first\n/* FIXME(bob): second */\n// TODO: third #7\n")
+
+	if got, want := sync(comments, true), []string{"create  TODO: first", "create  FIXME: second"}; !cmp.Equal(got, want) {
+		t.Errorf("Sync(dry run) \n%s", cmp.Diff(got, want))
+	}
+	if issues, _ := f.Find(Query{}); len(issues) != 0 {
+		t.Fatalf("Sync(dry run) created %v", issues)
+	}
+
+	if got, want := sync(comments, false), []string{"create #1 TODO: first", "create #2 FIXME: second"}; !cmp.Equal(got, want) {
+		t.Errorf("Sync() \n%s", cmp.Diff(got, want))
+	}
+
+	content, _ := os.ReadFile(source)
+	if want := "// TODO: first (#1)\n/* FIXME(bob): second (#2) */\n// TODO: third #7\n"; string(content) != want {
+		t.Errorf("Sync() wrote %q, want %q", content, want)
+	}
+
+	// Nothing to do once the references are written back.
+	if got := sync(scan(""), false); len(got) != 0 {
+		t.Errorf("Sync() again = %v", got)
+	}
+
+	// A changed comment updates its issue and a removed one closes it.
+	got := sync(scan("// TODO: first and more (#1)\n// TODO: third #7\n"), false)
+	if want := []string{"update #1 TODO: first and more", "close #2 FIXME: second"}; !cmp.Equal(got, want) {
+		t.Errorf("Sync() after changes \n%s", cmp.Diff(got, want))
+	}
+
+	// An issue created for a comment that wasn't written back is linked.
+	lost := todos.Comment{File: source, Line: 3, Type: "TODO", Text: "lost"}
+	if _, err := f.Create(Issue{Title: title(lost), Body: body(lost, diff.Fingerprint(lost), "a.go"), Labels: []string{DefaultLabel}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got = sync(scan("// TODO: first and more (#1)\n// TODO: third #7\n// TODO: lost\n"), false)
+	if want := []string{"link #3 TODO: lost"}; !cmp.Equal(got, want) {
+		t.Errorf("Sync() with lost reference \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestSyncPaths(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	for _, d := range []string{a, b} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(a, "x.go"), "// TODO: in a\n")
+	write(filepath.Join(b, "y.go"), "// TODO: in b\n")
+
+	f := NewFile(filepath.Join(dir, "issues.json"))
+
+	sync := func(paths ...string) []string {
+		t.Helper()
+		comments, err := todos.SearchPaths(paths, []string{"TODO"}, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions, err := Sync(f, comments, SyncOptions{Close: true, Paths: paths, Root: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, a := range actions {
+			got = append(got, a.Kind+" "+a.Issue.ID+" "+a.Issue.Title)
+		}
+		return got
+	}
+
+	if got, want := sync(a, b), []string{"create #1 TODO: in a", "create #2 TODO: in b"}; !cmp.Equal(got, want) {
+		t.Fatalf("Sync() \n%s", cmp.Diff(got, want))
+	}
+
+	// Syncing a subset leaves the issues of comments outside it open.
+	if got := sync(a); len(got) != 0 {
+		t.Errorf("Sync(a) = %v", got)
+	}
+
+	// A comment removed inside the subset still closes its issue.
+	write(filepath.Join(a, "x.go"), "package a\n")
+	if got, want := sync(a), []string{"close #1 TODO: in a"}; !cmp.Equal(got, want) {
+		t.Errorf("Sync(a) after removal \n%s", cmp.Diff(got, want))
+	}
+
+	if state, err := State(f, "#2"); err != nil || state != Open {
+		t.Errorf("State(#2) = %q, %v, want %q", state, err, Open)
+	}
+
+	// Issues record paths relative to the root, so an issue created from a
+	// subdirectory is closed by a sync of that directory from the root.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	chdir := func(dir string) {
+		t.Helper()
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chdir(a)
+	write("z.go", "// TODO: from a\n")
+	if got, want := sync("."), []string{"create #3 TODO: from a"}; !cmp.Equal(got, want) {
+		t.Fatalf("Sync(.) in a \n%s", cmp.Diff(got, want))
+	}
+
+	chdir(dir)
+	write(filepath.Join("a", "z.go"), "package a\n")
+	if got, want := sync("a"), []string{"close #3 TODO: from a"}; !cmp.Equal(got, want) {
+		t.Errorf("Sync(a) from the root \n%s", cmp.Diff(got, want))
+	}
+}
+
+func TestState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/app/issues/1":
+			io.WriteString(w, `{"number": 1, "state": "open"}`)
+		case "/api/v3/repos/acme/app/issues/2":
+			io.WriteString(w, `{"number": 2, "state": "closed", "pull_request": {}}`)
+		case "/api/v3/repos/acme/app/issues/500":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	g, err := New(Config{Type: "github", Repo: "acme/app", URL: server.URL + "/api/v3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{id: "#1", want: Open},
+		{id: "#2", want: Closed},
+		{id: "#3", want: Missing},
+		{id: "PROJ-4", want: ""},
+		{id: "#500", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := State(g, tt.id)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("State(%s) = %q, %v, want %q", tt.id, got, err, tt.want)
+		}
+	}
+}
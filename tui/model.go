@@ -0,0 +1,401 @@
+// Package tui implements an interactive terminal UI for browsing comments.
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/euforic/todos/todos"
+)
+
+// item is a row of the list: a group header or a comment. Depth is the
+// nesting level of the group.
+type item struct {
+	header  string
+	count   int
+	depth   int
+	comment *todos.Comment
+}
+
+// Model is the state of the UI. It is updated by HandleKey and drawn by View.
+type Model struct {
+	comments []todos.Comment
+	group    []string
+	sortBy   []todos.SortKey
+
+	filter    string
+	filtering bool
+
+	items  []item
+	cursor int
+	offset int
+
+	preview *Preview
+
+	// Quit is set when the user quits.
+	Quit bool
+	// Open is set to the comment to open in the editor. The caller clears it
+	// after opening the editor.
+	Open *todos.Comment
+}
+
+// NewModel returns a model showing comments grouped by the fields of group,
+// outermost first, and sorted by sortBy, in the same order as the -groupby
+// and -sortby output. Comments are grouped by file if group is empty.
+func NewModel(comments []todos.Comment, group []string, sortBy []todos.SortKey) *Model {
+	if len(group) == 0 {
+		group = []string{"file"}
+	}
+
+	m := &Model{
+		comments: append([]todos.Comment{}, comments...),
+		group:    group,
+		sortBy:   sortBy,
+		preview:  NewPreview(),
+	}
+
+	todos.SortComments(m.comments, sortBy)
+
+	m.rebuild()
+	return m
+}
+
+// Selected returns the comment under the cursor, or nil if there is none.
+func (m *Model) Selected() *todos.Comment {
+	if m.cursor < 0 || m.cursor >= len(m.items) {
+		return nil
+	}
+	return m.items[m.cursor].comment
+}
+
+// rebuild builds the list rows from the comments matching the filter and
+// moves the cursor to the first comment.
+func (m *Model) rebuild() {
+	matching := []todos.Comment{}
+	for _, c := range m.comments {
+		if fuzzyMatch(m.filter, searchText(c)) {
+			matching = append(matching, c)
+		}
+	}
+
+	m.items = m.items[:0]
+	m.addGroups(todos.GroupComments(matching, m.group, m.sortBy), 0)
+
+	m.cursor, m.offset = 0, 0
+	m.move(1)
+}
+
+// addGroups adds the rows of the groups and their subgroups.
+func (m *Model) addGroups(groups []todos.Group, depth int) {
+	for _, group := range groups {
+		m.items = append(m.items, item{header: group.Label(), count: group.Count, depth: depth})
+		m.addGroups(group.Groups, depth+1)
+		for i := range group.Comments {
+			m.items = append(m.items, item{comment: &group.Comments[i], depth: depth})
+		}
+	}
+}
+
+// setGroup groups the comments by a single field.
+func (m *Model) setGroup(field string) {
+	m.group = []string{field}
+	m.rebuild()
+}
+
+// nextGroup returns the group field after the outermost one in
+// todos.GroupFields.
+func (m *Model) nextGroup() string {
+	for i, field := range todos.GroupFields {
+		if field == m.group[0] {
+			return todos.GroupFields[(i+1)%len(todos.GroupFields)]
+		}
+	}
+	return todos.GroupFields[0]
+}
+
+// grouped reports whether the comments are grouped by field.
+func (m *Model) grouped(field string) bool {
+	for _, f := range m.group {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// move moves the cursor by delta comments, skipping group headers.
+func (m *Model) move(delta int) {
+	if len(m.items) == 0 {
+		m.cursor = 0
+		return
+	}
+
+	step := 1
+	if delta < 0 {
+		step, delta = -1, -delta
+	}
+
+	pos := m.cursor
+	// The cursor starts on a header after rebuild, which counts as the first step.
+	if m.items[pos].comment == nil && step > 0 {
+		delta--
+		for pos < len(m.items) && m.items[pos].comment == nil {
+			pos++
+		}
+	}
+
+	for ; delta > 0; delta-- {
+		next := pos + step
+		for next >= 0 && next < len(m.items) && m.items[next].comment == nil {
+			next += step
+		}
+		if next < 0 || next >= len(m.items) {
+			break
+		}
+		pos = next
+	}
+
+	if pos < len(m.items) && m.items[pos].comment != nil {
+		m.cursor = pos
+	}
+}
+
+// HandleKey updates the model for a key press.
+func (m *Model) HandleKey(key string) {
+	if m.filtering {
+		m.handleFilterKey(key)
+		return
+	}
+
+	switch key {
+	case "q", "ctrl+c":
+		m.Quit = true
+	case "j", "down", "ctrl+n":
+		m.move(1)
+	case "k", "up", "ctrl+p":
+		m.move(-1)
+	case "pgdown", "ctrl+d", " ":
+		m.move(10)
+	case "pgup", "ctrl+u":
+		m.move(-10)
+	case "g", "home":
+		m.cursor = 0
+		m.move(1)
+	case "G", "end":
+		m.move(len(m.items))
+	case "tab":
+		m.setGroup(m.nextGroup())
+	case "f":
+		m.setGroup("file")
+	case "a":
+		m.setGroup("author")
+	case "t":
+		m.setGroup("type")
+	case "/":
+		m.filtering = true
+	case "esc":
+		if m.filter != "" {
+			m.filter = ""
+			m.rebuild()
+		}
+	case "enter", "o", "e":
+		m.Open = m.Selected()
+	}
+}
+
+func (m *Model) handleFilterKey(key string) {
+	switch key {
+	case "enter":
+		m.filtering = false
+	case "esc", "ctrl+c":
+		m.filtering = false
+		m.filter = ""
+		m.rebuild()
+	case "backspace":
+		if m.filter != "" {
+			_, size := utf8.DecodeLastRuneInString(m.filter)
+			m.filter = m.filter[:len(m.filter)-size]
+			m.rebuild()
+		}
+	case "down", "up":
+		m.filtering = false
+		m.HandleKey(key)
+	default:
+		if r, size := utf8.DecodeRuneInString(key); size == len(key) && unicode.IsPrint(r) {
+			m.filter += key
+			m.rebuild()
+		}
+	}
+}
+
+// searchText is the text a comment is matched against when filtering.
+func searchText(c todos.Comment) string {
+	return fmt.Sprintf("%s %s %s:%d %s", c.Type, strings.Join(todos.Authors(c), " "), c.File, c.Line, c.Text)
+}
+
+// fuzzyMatch reports whether every space separated term of query appears in
+// text in order, not necessarily contiguously, ignoring case.
+func fuzzyMatch(query, text string) bool {
+	text = strings.ToLower(text)
+
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		rest := text
+		for _, r := range term {
+			i := strings.IndexRune(rest, r)
+			if i < 0 {
+				return false
+			}
+			rest = rest[i+utf8.RuneLen(r):]
+		}
+	}
+
+	return true
+}
+
+// View renders the model to fit a terminal of the given size.
+func (m *Model) View(width, height int) string {
+	if width < 20 || height < 6 {
+		return "terminal too small"
+	}
+
+	var b strings.Builder
+
+	title := fmt.Sprintf(" todos  %d comments  grouped by %s", len(m.comments), strings.Join(m.group, ", "))
+	if m.filter != "" || m.filtering {
+		title += "  filter: " + m.filter
+		if m.filtering {
+			title += "█"
+		}
+	}
+	b.WriteString(styleTitle(pad(title, width)))
+	b.WriteString("\r\n")
+
+	previewHeight := (height - 2) / 2
+	if previewHeight > 15 {
+		previewHeight = 15
+	}
+	listHeight := height - 2 - previewHeight
+
+	// Keep the cursor inside the visible part of the list.
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+listHeight {
+		m.offset = m.cursor - listHeight + 1
+	}
+	// Show the group header of the first comment when scrolled to the top.
+	if m.offset == 1 && m.items[0].comment == nil {
+		m.offset = 0
+	}
+
+	for row := 0; row < listHeight; row++ {
+		i := m.offset + row
+		if i < len(m.items) {
+			b.WriteString(m.renderItem(i, width))
+		} else if i == 0 {
+			b.WriteString(pad(" No comments match", width))
+		} else {
+			b.WriteString(pad("", width))
+		}
+		b.WriteString("\r\n")
+	}
+
+	b.WriteString(m.renderPreview(width, previewHeight))
+
+	help := " j/k move  enter open  / filter  tab group (f/a/t)  q quit"
+	if m.filtering {
+		help = " type to filter  enter done  esc clear"
+	}
+	b.WriteString(styleDim(pad(help, width)))
+
+	return b.String()
+}
+
+func (m *Model) renderItem(i, width int) string {
+	it := m.items[i]
+	if it.comment == nil {
+		return styleHeader(pad(fmt.Sprintf(" %s%s (%d)", indent(it.depth), it.header, it.count), width))
+	}
+
+	c := it.comment
+	author := ""
+	if c.Author != "" && !m.grouped("author") {
+		author = "(" + strings.Join(todos.Authors(*c), ", ") + ")"
+	}
+
+	location := fmt.Sprintf("%s:%d", c.File, c.Line)
+	if m.grouped("file") {
+		location = fmt.Sprintf("%d", c.Line)
+	}
+
+	line := pad(fmt.Sprintf("   %s%s%s: %s  %s", indent(it.depth), c.Type, author, c.Text, location), width)
+	if i == m.cursor {
+		return styleSelected(line)
+	}
+	return line
+}
+
+func (m *Model) renderPreview(width, height int) string {
+	var b strings.Builder
+
+	c := m.Selected()
+	if c == nil {
+		b.WriteString(styleTitle(pad("", width)))
+		b.WriteString("\r\n")
+		for i := 1; i < height; i++ {
+			b.WriteString(pad("", width))
+			b.WriteString("\r\n")
+		}
+		return b.String()
+	}
+
+	b.WriteString(styleTitle(pad(fmt.Sprintf(" %s:%d", filepath.ToSlash(c.File), c.Line), width)))
+	b.WriteString("\r\n")
+
+	lines := m.preview.Context(c.File, c.Line, height-1)
+	for i := 0; i < height-1; i++ {
+		if i >= len(lines) {
+			b.WriteString(pad("", width))
+			b.WriteString("\r\n")
+			continue
+		}
+
+		l := lines[i]
+		text := pad(fmt.Sprintf("%5d  %s", l.Number, expandTabs(l.Text)), width)
+		if l.Number == c.Line {
+			text = styleHighlight(text)
+		}
+		b.WriteString(text)
+		b.WriteString("\r\n")
+	}
+
+	return b.String()
+}
+
+// indent returns the indentation of a nesting level.
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// pad truncates or pads s with spaces to exactly width runes.
+func pad(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		runes := []rune(s)
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+func styleTitle(s string) string     { return "\033[7m" + s + "\033[0m" }
+func styleHeader(s string) string    { return "\033[1m" + s + "\033[0m" }
+func styleSelected(s string) string  { return "\033[30;46m" + s + "\033[0m" }
+func styleHighlight(s string) string { return "\033[33m" + s + "\033[0m" }
+func styleDim(s string) string       { return "\033[2m" + s + "\033[0m" }
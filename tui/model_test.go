@@ -0,0 +1,220 @@
+package tui
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/euforic/todos/todos"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  bool
+	}{
+		{query: "", text: "TODO alice main.go:1 fix", want: true},
+		{query: "fx", text: "TODO alice main.go:1 fix", want: true},
+		{query: "FIX", text: "TODO alice main.go:1 fix", want: true},
+		{query: "xf", text: "TODO alice main.go:1 fix", want: false},
+		{query: "ali mgo", text: "TODO alice main.go:1 fix", want: true},
+		{query: "ali bob", text: "TODO alice main.go:1 fix", want: false},
+		{query: "✓", text: "TODO done ✓", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.query, tt.text); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+// rows returns the list rows of the model as text, marking the cursor with >.
+func rows(m *Model) []string {
+	result := []string{}
+	for i, it := range m.items {
+		switch {
+		case it.comment == nil:
+			result = append(result, it.header)
+		case i == m.cursor:
+			result = append(result, "> "+it.comment.Text)
+		default:
+			result = append(result, "  "+it.comment.Text)
+		}
+	}
+	return result
+}
+
+func TestModel(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "b.go", Line: 3, Type: "FIXME", Text: "leak", Author: "alice"},
+		{File: "a.go", Line: 9, Type: "TODO", Text: "later"},
+		{File: "a.go", Line: 1, Type: "TODO", Text: "first", Author: "bob"},
+	}
+
+	m := NewModel(comments, nil, nil)
+
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{name: "Initial", want: []string{"a.go", "> first", "  later", "b.go", "  leak"}},
+		{name: "Down", keys: []string{"j"}, want: []string{"a.go", "  first", "> later", "b.go", "  leak"}},
+		{name: "SkipHeader", keys: []string{"down"}, want: []string{"a.go", "  first", "  later", "b.go", "> leak"}},
+		{name: "StopAtEnd", keys: []string{"j"}, want: []string{"a.go", "  first", "  later", "b.go", "> leak"}},
+		{name: "Up", keys: []string{"k", "k", "k"}, want: []string{"a.go", "> first", "  later", "b.go", "  leak"}},
+		{name: "End", keys: []string{"G"}, want: []string{"a.go", "  first", "  later", "b.go", "> leak"}},
+		{name: "GroupByAuthor", keys: []string{"a"}, want: []string{"alice", "> leak", "bob", "  first", "(no author)", "  later"}},
+		{name: "GroupByType", keys: []string{"tab"}, want: []string{"FIXME", "> leak", "TODO", "  first", "  later"}},
+		{name: "Filter", keys: []string{"/", "a", "t", "r"}, want: []string{"TODO", "> later"}},
+		{name: "FilterBackspace", keys: []string{"backspace"}, want: []string{"TODO", "> first", "  later"}},
+		{name: "FilterDone", keys: []string{"enter", "j"}, want: []string{"TODO", "  first", "> later"}},
+		{name: "FilterNoMatch", keys: []string{"/", "z", "enter"}, want: []string{}},
+		{name: "FilterClear", keys: []string{"esc", "f"}, want: []string{"a.go", "> first", "  later", "b.go", "  leak"}},
+		{name: "TabCycles", keys: []string{"t", "tab"}, want: []string{".", "> first", "  later", "  leak"}},
+	}
+
+	for _, tt := range tests {
+		for _, key := range tt.keys {
+			m.HandleKey(key)
+		}
+
+		if got := rows(m); !cmp.Equal(got, tt.want) {
+			t.Errorf("%s: rows \n%s", tt.name, cmp.Diff(got, tt.want))
+		}
+	}
+
+	m.HandleKey("enter")
+	if m.Open == nil || m.Open.Text != "first" {
+		t.Errorf("enter opened %+v, want first", m.Open)
+	}
+
+	m.HandleKey("q")
+	if !m.Quit {
+		t.Error("q did not quit")
+	}
+}
+
+func TestModelGroups(t *testing.T) {
+	comments := []todos.Comment{
+		{File: "file10.go", Line: 1, Type: "TODO", Text: "ten", Author: "alice", Authors: []string{"alice", "bob"}},
+		{File: "file2.go", Line: 5, Type: "TODO", Text: "five", Author: "bob", Authors: []string{"bob"}},
+		{File: "file2.go", Line: 1, Type: "FIXME", Text: "one"},
+	}
+
+	tests := []struct {
+		name   string
+		group  []string
+		sortBy []todos.SortKey
+		want   []string
+	}{
+		{name: "NaturalFileOrder", group: []string{"file"}, want: []string{"file2.go", "> one", "  five", "file10.go", "  ten"}},
+		{name: "SortKeys", group: []string{"file"}, sortBy: []todos.SortKey{{Field: "line", Desc: true}}, want: []string{"file2.go", "> five", "  one", "file10.go", "  ten"}},
+		{name: "EveryAuthor", group: []string{"author"}, want: []string{"alice", "> ten", "bob", "  five", "  ten", "(no author)", "  one"}},
+		{name: "Nested", group: []string{"type", "author"}, want: []string{"FIXME", "(no author)", "> one", "TODO", "alice", "  ten", "bob", "  five", "  ten"}},
+		{name: "Dir", group: []string{"dir"}, want: []string{".", "> one", "  five", "  ten"}},
+		{name: "AuthorsDesc", group: []string{"author"}, sortBy: []todos.SortKey{{Field: "author", Desc: true}}, want: []string{"bob", "> five", "  ten", "alice", "  ten", "(no author)", "  one"}},
+	}
+
+	for _, tt := range tests {
+		m := NewModel(comments, tt.group, tt.sortBy)
+		if got := rows(m); !cmp.Equal(got, tt.want) {
+			t.Errorf("%s: rows \n%s", tt.name, cmp.Diff(got, tt.want))
+		}
+	}
+}
+
+func TestModelView(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n\nfunc main() {\n\t// TODO: wire flags\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel([]todos.Comment{{File: file, Line: 4, Type: "TODO", Text: "wire flags"}}, []string{"type"}, nil)
+	view := m.View(60, 12)
+
+	lines := strings.Split(view, "\r\n")
+	if len(lines) != 12 {
+		t.Fatalf("View() has %d lines, want 12", len(lines))
+	}
+
+	for _, want := range []string{"1 comments", "TODO (1)", "wire flags", "    4      // TODO: wire flags"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() does not contain %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestPreviewContext(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "lines.txt")
+	if err := os.WriteFile(file, []byte("1\n2\n3\n4\n5\n6\n7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line int
+		n    int
+		want []int
+	}{
+		{line: 4, n: 3, want: []int{3, 4, 5}},
+		{line: 1, n: 3, want: []int{1, 2, 3}},
+		{line: 7, n: 3, want: []int{5, 6, 7}},
+		{line: 4, n: 20, want: []int{1, 2, 3, 4, 5, 6, 7}},
+	}
+
+	p := NewPreview()
+	for _, tt := range tests {
+		got := []int{}
+		for _, l := range p.Context(file, tt.line, tt.n) {
+			got = append(got, l.Number)
+		}
+
+		if !cmp.Equal(got, tt.want) {
+			t.Errorf("Context(%d, %d) = %v, want %v", tt.line, tt.n, got, tt.want)
+		}
+	}
+
+	if got := p.Context(filepath.Join(dir, "missing"), 1, 3); got != nil {
+		t.Errorf("Context() of missing file = %v", got)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("j\r\x1b[A\x1b[6~\x7f\x03é/"))
+
+	want := []string{"j", "enter", "up", "pgdown", "backspace", "ctrl+c", "é", "/"}
+	for _, w := range want {
+		got, err := readKey(in)
+		if err != nil || got != w {
+			t.Fatalf("readKey() = %q, %v, want %q", got, err, w)
+		}
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		editor string
+		want   []string
+	}{
+		{editor: "", want: []string{"vi", "+12", "main.go"}},
+		{editor: "nvim", want: []string{"nvim", "+12", "main.go"}},
+		{editor: "code --wait", want: []string{"code", "--wait", "--goto", "main.go:12"}},
+		{editor: "/usr/bin/subl", want: []string{"/usr/bin/subl", "main.go:12"}},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("VISUAL", "")
+		t.Setenv("EDITOR", tt.editor)
+
+		if got := EditorCommand("main.go", 12).Args; !cmp.Equal(got, tt.want) {
+			t.Errorf("EditorCommand() with EDITOR=%q = %v, want %v", tt.editor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"bufio"
+	"os"
+)
+
+// Line is a numbered line of a source file.
+type Line struct {
+	Number int
+	Text   string
+}
+
+// Preview reads and caches the source files shown in the preview pane.
+type Preview struct {
+	files map[string][]string
+}
+
+// NewPreview returns an empty preview cache.
+func NewPreview() *Preview {
+	return &Preview{files: map[string][]string{}}
+}
+
+// Context returns up to n lines of path centered on line. It returns nil if
+// the file cannot be read.
+func (p *Preview) Context(path string, line, n int) []Line {
+	lines, ok := p.files[path]
+	if !ok {
+		lines = readLines(path)
+		p.files[path] = lines
+	}
+	if len(lines) == 0 || n <= 0 {
+		return nil
+	}
+
+	start := line - 1 - n/2
+	if start > len(lines)-n {
+		start = len(lines) - n
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	result := []Line{}
+	for i := start; i < len(lines) && len(result) < n; i++ {
+		result = append(result, Line{Number: i + 1, Text: lines[i]})
+	}
+	return result
+}
+
+// Forget drops the cached copy of path so it is read again.
+func (p *Preview) Forget(path string) {
+	delete(p.files, path)
+}
+
+func readLines(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
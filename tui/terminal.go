@@ -0,0 +1,230 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/euforic/todos/todos"
+)
+
+// ErrNoTerminal is returned by Run when there is no terminal to draw on.
+var ErrNoTerminal = errors.New("tui: no terminal available")
+
+// Run shows the comments in the terminal until the user quits.
+func Run(comments []todos.Comment, group []string, sortBy []todos.SortKey) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return ErrNoTerminal
+	}
+	defer tty.Close()
+
+	term := &terminal{tty: tty}
+	if err := term.start(); err != nil {
+		return err
+	}
+	defer term.stop()
+
+	m := NewModel(comments, group, sortBy)
+	in := bufio.NewReader(tty)
+
+	for !m.Quit {
+		width, height := term.size()
+		fmt.Fprint(tty, "\033[H"+m.View(width, height))
+
+		key, err := readKey(in)
+		if err != nil {
+			return err
+		}
+		m.HandleKey(key)
+
+		if c := m.Open; c != nil {
+			m.Open = nil
+			if err := term.edit(c.File, c.Line); err != nil {
+				return err
+			}
+			m.preview.Forget(c.File)
+		}
+	}
+
+	return nil
+}
+
+// terminal switches a tty in and out of raw mode using stty.
+type terminal struct {
+	tty   *os.File
+	saved string
+}
+
+func (t *terminal) start() error {
+	saved, err := t.stty("-g")
+	if err != nil {
+		return ErrNoTerminal
+	}
+	t.saved = strings.TrimSpace(saved)
+
+	if _, err := t.stty("raw", "-echo"); err != nil {
+		return err
+	}
+
+	// Switch to the alternate screen and hide the cursor.
+	fmt.Fprint(t.tty, "\033[?1049h\033[?25l\033[2J")
+	return nil
+}
+
+func (t *terminal) stop() {
+	fmt.Fprint(t.tty, "\033[?25h\033[?1049l")
+	_, _ = t.stty(t.saved)
+}
+
+// size returns the width and height of the terminal.
+func (t *terminal) size() (int, int) {
+	out, err := t.stty("size")
+	if err != nil {
+		return 80, 24
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 80, 24
+	}
+
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows == 0 || cols == 0 {
+		return 80, 24
+	}
+	return cols, rows
+}
+
+// edit restores the terminal, runs the editor and enters raw mode again.
+func (t *terminal) edit(file string, line int) error {
+	t.stop()
+
+	cmd := EditorCommand(file, line)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = t.tty, t.tty, t.tty
+	runErr := cmd.Run()
+
+	if err := t.start(); err != nil {
+		return err
+	}
+	if runErr != nil {
+		return fmt.Errorf("tui: running editor: %w", runErr)
+	}
+	return nil
+}
+
+func (t *terminal) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = t.tty
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// EditorCommand returns the command opening file at line in the editor named
+// by $VISUAL or $EDITOR, falling back to vi.
+func EditorCommand(file string, line int) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	fields := strings.Fields(editor)
+	args := fields[1:]
+
+	switch strings.TrimSuffix(filepath.Base(fields[0]), ".exe") {
+	case "code", "code-insiders", "codium":
+		args = append(args, "--goto", fmt.Sprintf("%s:%d", file, line))
+	case "subl", "zed":
+		args = append(args, fmt.Sprintf("%s:%d", file, line))
+	default:
+		args = append(args, fmt.Sprintf("+%d", line), file)
+	}
+
+	return exec.Command(fields[0], args...)
+}
+
+// readKey reads a key press and returns its name: "up", "down", "pgup",
+// "pgdown", "home", "end", "enter", "tab", "esc", "backspace", "ctrl+x" or
+// the typed character.
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case b == '\r' || b == '\n':
+		return "enter", nil
+	case b == '\t':
+		return "tab", nil
+	case b == 127 || b == 8:
+		return "backspace", nil
+	case b == 27:
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		return readEscape(r)
+	case b < 32:
+		return "ctrl+" + string(rune('a'+b-1)), nil
+	case b < utf8.RuneSelf:
+		return string(rune(b)), nil
+	}
+
+	if err := r.UnreadByte(); err != nil {
+		return "", err
+	}
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	return string(c), nil
+}
+
+// readEscape reads the rest of an escape sequence after ESC.
+func readEscape(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if b != '[' && b != 'O' {
+		return "esc", nil
+	}
+
+	seq := ""
+	for r.Buffered() > 0 {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		seq += string(rune(c))
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+
+	switch seq {
+	case "A":
+		return "up", nil
+	case "B":
+		return "down", nil
+	case "H", "1~", "7~":
+		return "home", nil
+	case "F", "4~", "8~":
+		return "end", nil
+	case "5~":
+		return "pgup", nil
+	case "6~":
+		return "pgdown", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/euforic/todos/todos"
+	"github.com/euforic/todos/tui"
+)
+
+var tuiCommand = &command{
+	name:    "tui",
+	usage:   "tui [options] [path...]",
+	summary: "Browse comments interactively and open them in $EDITOR",
+	run:     runTUI,
+}
+
+// runTUI runs the tui command
+func runTUI(cmd *command, args []string) error {
+	fs := newFlagSet(cmd)
+	flags := defineSearchFlags(fs)
+	defineFilterFlags(fs, flags)
+	fs.StringVar(&flags.groupBy, "groupby", "", "Comma-separated fields to group comments by, outermost first (file, author, type, dir, tag, owner, severity) (default: file)")
+	fs.StringVar(&flags.sortBy, "sortby", "", "Comma-separated fields to sort comments by within their group (author, file, line, type, text, issue, language, owner, severity), each optionally postfixed with ':desc'")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, comments, err := findComments(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	groupBy, err := todos.ParseGroupBy(cfg.GroupBy)
+	if err != nil {
+		return err
+	}
+
+	sortBy, err := todos.ParseSortKeys(cfg.SortBy)
+	if err != nil {
+		return err
+	}
+
+	return tui.Run(comments, groupBy, sortBy)
+}
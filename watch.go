@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/euforic/todos/filter"
+	"github.com/euforic/todos/todos"
+)
+
+var errWatchStdin = errors.New("-watch cannot be combined with -stdin")
+
+// watchFlags holds the values of the watch mode flags
+type watchFlags struct {
+	enabled  bool
+	interval time.Duration
+	deltas   bool
+}
+
+// defineWatchFlags defines the watch mode flags
+func defineWatchFlags(fs *flag.FlagSet) *watchFlags {
+	v := &watchFlags{}
+	fs.BoolVar(&v.enabled, "watch", false, "Keep running and re-scan files when they change")
+	fs.DurationVar(&v.interval, "watch-interval", time.Second, "How often to poll files for changes in watch mode")
+	fs.BoolVar(&v.deltas, "watch-deltas", false, "Print added and removed comments instead of the full output in watch mode")
+	return v
+}
+
+// watchComments polls the search paths for changes until interrupted,
+// re-rendering the output or printing the changes after each change
+func watchComments(fs *flag.FlagSet, flags *searchFlags, watch *watchFlags) error {
+	if flags.stdin {
+		return errWatchStdin
+	}
+
+	paths, err := searchPaths(fs, flags)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(fs, flags, configDir(paths))
+	if err != nil {
+		return err
+	}
+
+	ignoreList, err := buildIgnoreList(cfg, paths)
+	if err != nil {
+		return err
+	}
+
+	f, err := filter.Parse(cfg.Filter)
+	if err != nil {
+		return err
+	}
+
+	annotator, err := loadAnnotator(cfg, configDir(paths))
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	m, err := newMatcher(cfg)
+	if err != nil {
+		return err
+	}
+
+	watcher := m.NewWatcher(paths, ignoreList)
+	clearScreen := isTerminal(os.Stdout)
+
+	var outputErr error
+	first := true
+	err = watcher.Watch(ctx, watch.interval, func(added, removed []todos.Comment) {
+		annotator.annotate(added)
+		annotator.annotate(removed)
+		added, removed = f.Apply(added), f.Apply(removed)
+		if !first && len(added) == 0 && len(removed) == 0 {
+			return
+		}
+		first = false
+
+		if watch.deltas {
+			printDeltas(added, removed)
+			return
+		}
+
+		if clearScreen {
+			// Move the cursor home and clear the screen.
+			fmt.Print("\033[H\033[2J")
+		}
+
+		comments := watcher.Comments()
+		annotator.annotate(comments)
+		if err := outputComments(cfg, f.Apply(comments)); err != nil {
+			outputErr = err
+			stop()
+		}
+	})
+	if outputErr != nil {
+		return outputErr
+	}
+
+	return err
+}
+
+// printDeltas prints the added and removed comments with a +/- prefix
+func printDeltas(added, removed []todos.Comment) {
+	stamp := time.Now().Format("15:04:05")
+	for _, c := range removed {
+		fmt.Printf("%s - %s:%d %s\n", stamp, c.File, c.Line, todos.Summary(c))
+	}
+	for _, c := range added {
+		fmt.Printf("%s + %s:%d %s\n", stamp, c.File, c.Line, todos.Summary(c))
+	}
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}